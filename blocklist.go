@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// errMissingBlockEntry is returned when adding to or removing from a blocklist without an
+// artist, song or pattern.
+var errMissingBlockEntry = errors.New("an artist, song or pattern is required")
+
+// Blocklist describes the songs a streamer refuses to have added to their setlists.
+type Blocklist struct {
+	// Artists are blocked artists, matched case-insensitively.
+	Artists []string `json:"artists,omitempty"`
+	// Songs are blocked songs, matched by artist and name.
+	Songs []*Song `json:"songs,omitempty"`
+	// Patterns are regular expressions matched case-insensitively against songs in the
+	// form "Artist - Song".
+	Patterns []string `json:"patterns,omitempty"`
+	// MaxDuration blocks songs longer than the number of seconds. Songs whose durations
+	// aren't known aren't blocked.
+	MaxDuration int `json:"max_duration,omitempty"`
+	// MaxDifficulty blocks songs rated above a difficulty for an instrument, keyed by
+	// instrument. Songs without a rating for the instrument aren't blocked.
+	MaxDifficulty map[string]int `json:"max_difficulty,omitempty"`
+	// Cooldown keeps songs from being requested again too soon after they're played.
+	Cooldown *Cooldown `json:"cooldown,omitempty"`
+
+	// patterns are the compiled Patterns, set by compile when the blocklist is loaded to
+	// check songs against.
+	patterns []*regexp.Regexp
+}
+
+// loadBlocklist looks up the streamer's blocklist and compiles its patterns so they're
+// compiled once rather than for every song checked against it.
+func loadBlocklist(ctx context.Context, db blocker) (*Blocklist, error) {
+	b, err := db.blocklist(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("looking up blocklist: %w", err)
+	}
+	if err := b.compile(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// compile compiles the blocklist's patterns for check, returning an error if any of them
+// aren't valid regular expressions.
+func (b *Blocklist) compile() error {
+	if b == nil {
+		return nil
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(b.Patterns))
+	for _, p := range b.Patterns {
+		re, err := compilePattern(p)
+		if err != nil {
+			return fmt.Errorf("compiling blocklist pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	b.patterns = patterns
+
+	return nil
+}
+
+// compilePattern compiles a blocklist pattern, which matches case-insensitively.
+func compilePattern(p string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + p)
+}
+
+// check returns the reason the song is blocked or an empty string if it isn't. Only
+// patterns compiled by compile are matched.
+func (b *Blocklist) check(s *Song) string {
+	if b == nil {
+		return ""
+	}
+
+	for _, a := range b.Artists {
+		if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(s.Artist)) {
+			return fmt.Sprintf("songs by %s are blocked", s.Artist)
+		}
+	}
+	for _, blocked := range b.Songs {
+		if songKey(blocked) == songKey(s) {
+			return fmt.Sprintf("%q by %s is blocked", s.Name, s.Artist)
+		}
+	}
+	for _, re := range b.patterns {
+		if re.MatchString(chatSong(s)) {
+			return fmt.Sprintf("%q by %s is blocked", s.Name, s.Artist)
+		}
+	}
+
+	if b.MaxDuration > 0 && s.Duration > b.MaxDuration {
+		return fmt.Sprintf(
+			"%q is %s long, over the %s limit",
+			s.Name, time.Duration(s.Duration)*time.Second, time.Duration(b.MaxDuration)*time.Second,
+		)
+	}
+	for instrument, limit := range b.MaxDifficulty {
+		if d, ok := s.Difficulties[instrument]; ok && d > limit {
+			return fmt.Sprintf("%q is rated %d on %s, over the limit of %d", s.Name, d, instrument, limit)
+		}
+	}
+
+	return ""
+}
+
+// blockEntry is an artist, song or pattern being added to or removed from a blocklist.
+// Only one of Pattern, Song (with Artist) or Artist is used, in that order.
+type blockEntry struct {
+	Artist  string
+	Song    string
+	Pattern string
+}
+
+// add adds the entry to the blocklist, doing nothing if it's already blocked. Patterns
+// that aren't valid regular expressions are returned as an error.
+func (b *Blocklist) add(e *blockEntry) error {
+	switch {
+	case e.Pattern != "":
+		if _, err := compilePattern(e.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !slices.Contains(b.Patterns, e.Pattern) {
+			b.Patterns = append(b.Patterns, e.Pattern)
+		}
+	case e.Song != "":
+		s := &Song{Artist: e.Artist, Name: e.Song}
+		if !slices.ContainsFunc(b.Songs, func(blocked *Song) bool { return songKey(blocked) == songKey(s) }) {
+			b.Songs = append(b.Songs, s)
+		}
+	case e.Artist != "":
+		if !slices.ContainsFunc(b.Artists, func(a string) bool { return strings.EqualFold(a, e.Artist) }) {
+			b.Artists = append(b.Artists, e.Artist)
+		}
+	default:
+		return errMissingBlockEntry
+	}
+
+	return nil
+}
+
+// remove removes the entry from the blocklist.
+func (b *Blocklist) remove(e *blockEntry) error {
+	switch {
+	case e.Pattern != "":
+		b.Patterns = slices.DeleteFunc(b.Patterns, func(p string) bool { return p == e.Pattern })
+	case e.Song != "":
+		s := &Song{Artist: e.Artist, Name: e.Song}
+		b.Songs = slices.DeleteFunc(b.Songs, func(blocked *Song) bool { return songKey(blocked) == songKey(s) })
+	case e.Artist != "":
+		b.Artists = slices.DeleteFunc(b.Artists, func(a string) bool { return strings.EqualFold(a, e.Artist) })
+	default:
+		return errMissingBlockEntry
+	}
+
+	return nil
+}
+
+// checkBlocklist returns a refusalError if the song is on the streamer's blocklist or,
+// unless the cooldown is overridden, was played too recently to be requested again.
+func checkBlocklist(ctx context.Context, db restricter, s *Song, override bool) error {
+	b, err := loadBlocklist(ctx, db)
+	if err != nil {
+		return err
+	}
+	if reason := b.check(s); reason != "" {
+		return &refusalError{reason: reason}
+	}
+	if b == nil || override {
+		return nil
+	}
+
+	reason, err := checkCooldown(ctx, db, b.Cooldown, s, time.Now())
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return &refusalError{reason: reason}
+	}
+
+	return nil
+}
+
+// unblocked returns the charts in the library whose songs aren't blocked.
+func (b *Blocklist) unblocked(library []*Chart) []*Chart {
+	if b == nil {
+		return library
+	}
+
+	return slices.DeleteFunc(slices.Clone(library), func(c *Chart) bool { return b.check(&c.Song) != "" })
+}
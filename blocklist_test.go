@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklistCheck(t *testing.T) {
+	b := &Blocklist{
+		Artists:       []string{"nickelback"},
+		Songs:         []*Song{{Artist: "Sum 41", Name: "Fat Lip"}},
+		Patterns:      []string{`\(live\)$`},
+		MaxDuration:   420,
+		MaxDifficulty: map[string]int{"guitar": 5},
+	}
+	require.NoError(t, b.compile())
+
+	testCases := []struct {
+		name     string
+		song     *Song
+		expected string
+	}{
+		{
+			name:     "blocks artists regardless of case",
+			song:     &Song{Artist: "Nickelback", Name: "Photograph"},
+			expected: "songs by Nickelback are blocked",
+		},
+		{
+			name:     "blocks songs",
+			song:     &Song{Artist: "sum 41", Name: "fat lip"},
+			expected: `"fat lip" by sum 41 is blocked`,
+		},
+		{
+			name:     "blocks songs matching patterns",
+			song:     &Song{Artist: "Trivium", Name: "Down From the Sky (Live)"},
+			expected: `"Down From the Sky (Live)" by Trivium is blocked`,
+		},
+		{
+			name:     "blocks songs over the duration limit",
+			song:     &Song{Artist: "DragonForce", Name: "Through the Fire and Flames", Duration: 441},
+			expected: `"Through the Fire and Flames" is 7m21s long, over the 7m0s limit`,
+		},
+		{
+			name:     "blocks songs over the difficulty limit",
+			song:     &Song{Artist: "Trivium", Name: "Down From the Sky", Difficulties: map[string]int{"guitar": 6, "drums": 6}},
+			expected: `"Down From the Sky" is rated 6 on guitar, over the limit of 5`,
+		},
+		{
+			name: "allows songs without known durations or ratings",
+			song: &Song{Artist: "Trivium", Name: "Down From the Sky", Difficulties: map[string]int{"drums": 6}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, b.check(tc.song))
+		})
+	}
+
+	var none *Blocklist
+	assert.Empty(t, none.check(&Song{Artist: "Nickelback", Name: "Photograph"}))
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	db := NewMockdber(t)
+	db.On("blocklist", mock.Anything).Return(&Blocklist{Patterns: []string{`\(live\)$`}}, nil).Once()
+	db.On("blocklist", mock.Anything).Return(&Blocklist{Patterns: []string{`(live`}}, nil).Once()
+
+	b, err := loadBlocklist(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, `"Down From the Sky (Live)" by Trivium is blocked`, b.check(&Song{Artist: "Trivium", Name: "Down From the Sky (Live)"}))
+
+	_, err = loadBlocklist(context.Background(), db)
+	assert.ErrorContains(t, err, `compiling blocklist pattern "(live"`)
+}
+
+func TestBlocklistAddRemove(t *testing.T) {
+	b := &Blocklist{}
+
+	require.NoError(t, b.add(&blockEntry{Artist: "Nickelback"}))
+	require.NoError(t, b.add(&blockEntry{Artist: "nickelback"}))
+	require.NoError(t, b.add(&blockEntry{Artist: "Sum 41", Song: "Fat Lip"}))
+	require.NoError(t, b.add(&blockEntry{Pattern: `\(live\)$`}))
+	assert.Equal(t, &Blocklist{
+		Artists:  []string{"Nickelback"},
+		Songs:    []*Song{{Artist: "Sum 41", Name: "Fat Lip"}},
+		Patterns: []string{`\(live\)$`},
+	}, b)
+
+	assert.ErrorContains(t, b.add(&blockEntry{Pattern: "(live"}), "invalid pattern")
+	assert.ErrorIs(t, b.add(&blockEntry{}), errMissingBlockEntry)
+
+	require.NoError(t, b.remove(&blockEntry{Artist: "NICKELBACK"}))
+	require.NoError(t, b.remove(&blockEntry{Artist: "sum 41", Song: "fat lip"}))
+	require.NoError(t, b.remove(&blockEntry{Pattern: `\(live\)$`}))
+	assert.Equal(t, &Blocklist{Artists: []string{}, Songs: []*Song{}, Patterns: []string{}}, b)
+}
+
+func TestAddBlock(t *testing.T) {
+	testCases := []struct {
+		name               string
+		query              string
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "blocks song on existing blocklist",
+			query: "?artist=Sum%2041&song=Fat%20Lip",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("blocklist", mock.Anything).Return(&Blocklist{Artists: []string{"Nickelback"}}, nil)
+				db.On("block", mock.Anything, &Blocklist{
+					Artists: []string{"Nickelback"},
+					Songs:   []*Song{{Artist: "Sum 41", Name: "Fat Lip"}},
+				}).Return(nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"artists":["Nickelback"],"songs":[{"artist":"Sum 41","name":"Fat Lip"}]}`,
+		},
+		{
+			name:  "refuses invalid patterns",
+			query: "?pattern=(live",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"invalid pattern: error parsing regexp: missing closing ): ` + "`(?i)(live`" + `"}`,
+		},
+		{
+			name:               "requires artist with song",
+			query:              "?song=Fat%20Lip",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"an artist is required with a song"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/blocklist/add" + tc.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
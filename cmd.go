@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// libraryCommand runs the library subcommand, which reads a streamer's charts from their
+// song folders and/or Clone Hero songcache and uploads them as their library.
+//
+//	songvoyage library upload -streamer name [-server url] [-songcache file] [dir...]
+func libraryCommand(args []string) error {
+	if len(args) == 0 || args[0] != "upload" {
+		return errors.New("usage: songvoyage library upload -streamer name [-server url] [-songcache file] [dir...]")
+	}
+
+	fset := flag.NewFlagSet("library upload", flag.ExitOnError)
+	server := fset.String("server", "http://localhost:8080", "songvoyage server to upload to")
+	streamer := fset.String("streamer", "", "streamer whose library is being uploaded")
+	songCache := fset.String("songcache", "", "Clone Hero songcache file to read charts from")
+	fset.Parse(args[1:]) // nolint: errcheck
+
+	if *streamer == "" {
+		return errors.New("a streamer is required")
+	}
+	if *songCache == "" && fset.NArg() == 0 {
+		return errors.New("a songcache or at least one song directory is required")
+	}
+
+	var charts []*Chart
+	if *songCache != "" {
+		f, err := os.Open(*songCache)
+		if err != nil {
+			return fmt.Errorf("opening songcache: %w", err)
+		}
+		defer f.Close()
+
+		if charts, err = parseSongCache(f); err != nil {
+			return fmt.Errorf("parsing songcache: %w", err)
+		}
+	}
+
+	for _, dir := range fset.Args() {
+		dirCharts, err := walkSongDirs(dir)
+		if err != nil {
+			return fmt.Errorf("reading songs in %q: %w", dir, err)
+		}
+		charts = append(charts, dirCharts...)
+	}
+
+	log.Printf("uploading %d charts", len(charts))
+	if err := uploadLibrary(*server, *streamer, charts); err != nil {
+		return fmt.Errorf("uploading library: %w", err)
+	}
+
+	return nil
+}
+
+// tipCommand runs the tip subcommand, which signs and sends a tip event to a server's tips
+// webhook the same way an external service would, for trying out tip tiers locally.
+//
+//	songvoyage tip send -streamer name -secret s -kind bits -amount 500 -artist a -song s [-user u] [-server url]
+func tipCommand(args []string) error {
+	if len(args) == 0 || args[0] != "send" {
+		return errors.New("usage: songvoyage tip send -streamer name -secret s -kind bits -amount 500 -artist a -song s [-user u] [-server url]")
+	}
+
+	fset := flag.NewFlagSet("tip send", flag.ExitOnError)
+	server := fset.String("server", "http://localhost:8080", "songvoyage server to send to")
+	secret := fset.String("secret", os.Getenv("SONGVOYAGE_TIP_SECRET"), "secret to sign the event with")
+	e := &tipEvent{}
+	fset.StringVar(&e.Streamer, "streamer", "", "streamer the tip is for")
+	fset.StringVar(&e.Kind, "kind", "tip", "kind of event, e.g. tip or bits")
+	fset.StringVar(&e.User, "user", "", "viewer who sent the tip")
+	fset.IntVar(&e.Amount, "amount", 0, "amount of the tip in the kind's unit")
+	fset.StringVar(&e.Artist, "artist", "", "artist of the requested song")
+	fset.StringVar(&e.Song, "song", "", "name of the requested song")
+	fset.Parse(args[1:]) // nolint: errcheck
+
+	if e.Streamer == "" || *secret == "" {
+		return errors.New("a streamer and secret are required")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	if err := sendTip(client, *server, []byte(*secret), e); err != nil {
+		return fmt.Errorf("sending tip: %w", err)
+	}
+
+	return nil
+}
+
+// discordCommand runs the discord subcommand, which registers the bot's slash commands with
+// a Discord application. Commands registered with a guild are available right away while
+// global commands can take a while to show up.
+//
+//	songvoyage discord register -app id -token t [-guild id]
+func discordCommand(args []string) error {
+	if len(args) == 0 || args[0] != "register" {
+		return errors.New("usage: songvoyage discord register -app id -token t [-guild id]")
+	}
+
+	fset := flag.NewFlagSet("discord register", flag.ExitOnError)
+	app := fset.String("app", "", "ID of the Discord application")
+	token := fset.String("token", os.Getenv("SONGVOYAGE_DISCORD_TOKEN"), "bot token of the Discord application")
+	guild := fset.String("guild", "", "ID of the Discord server to register commands with, registered globally when empty")
+	fset.Parse(args[1:]) // nolint: errcheck
+
+	if *app == "" || *token == "" {
+		return errors.New("an app and token are required")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if err := registerDiscordCommands(client, discordAPI, *app, *guild, *token); err != nil {
+		return fmt.Errorf("registering commands: %w", err)
+	}
+
+	return nil
+}
+
+// walkSongDirs loads a chart from every directory under root containing a song.ini.
+// Directories that can't be loaded are logged and skipped so one broken chart doesn't
+// stop a library of thousands from being uploaded.
+func walkSongDirs(root string) ([]*Chart, error) {
+	var charts []*Chart
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "song.ini" {
+			return nil
+		}
+
+		c, err := loadChartDir(filepath.Dir(path))
+		if err != nil {
+			log.Printf("skipping %q: %s", filepath.Dir(path), err)
+			return nil
+		}
+		charts = append(charts, c)
+
+		return nil
+	})
+
+	return charts, err
+}
+
+// uploadLibrary sends charts to the server as the streamer's library.
+func uploadLibrary(server, streamer string, charts []*Chart) error {
+	b, err := json.Marshal(charts)
+	if err != nil {
+		return fmt.Errorf("marshalling charts: %w", err)
+	}
+
+	u := server + "/v1/library/?" + url.Values{"streamer": {streamer}}.Encode()
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Post(u, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Cooldown describes how long after being played a song can't be requested again. Songs
+// are available again once both parts of the cooldown have passed.
+type Cooldown struct {
+	// Streams is the number of streams, including the current one, a song can't be played
+	// again within.
+	Streams int `json:"streams,omitempty"`
+	// Minutes is the number of minutes after being played a song can't be played again.
+	Minutes int `json:"minutes,omitempty"`
+}
+
+// checkCooldown returns the reason the song can't be requested yet, including how long
+// until it can be, or an empty string if it can be now.
+func checkCooldown(ctx context.Context, db cooldowner, c *Cooldown, s *Song, now time.Time) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+
+	var waits []string
+	if c.Minutes > 0 {
+		window := time.Duration(c.Minutes) * time.Minute
+		plays, err := db.history(ctx, historyQuery{Since: now.Add(-window)})
+		if err != nil {
+			return "", fmt.Errorf("looking up history: %w", err)
+		}
+		if last := lastPlayed(plays, s); !last.IsZero() {
+			waits = append(waits, last.Add(window).Sub(now).Round(time.Second).String())
+		}
+	}
+	if c.Streams > 0 {
+		open, err := db.session(ctx, "")
+		if err != nil {
+			return "", fmt.Errorf("looking up open session: %w", err)
+		}
+		plays, err := db.history(ctx, historyQuery{Streams: c.Streams})
+		if err != nil {
+			return "", fmt.Errorf("looking up history: %w", err)
+		}
+		if ago := streamsAgo(plays, open, s); ago >= 0 {
+			left := c.Streams - ago
+			if left == 1 {
+				waits = append(waits, "1 stream")
+			} else {
+				waits = append(waits, fmt.Sprintf("%d streams", left))
+			}
+		}
+	}
+
+	if len(waits) == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprintf(
+		"%q by %s was played recently, it can be requested again in %s",
+		s.Name, s.Artist, strings.Join(waits, " and "),
+	), nil
+}
+
+// lastPlayed returns when the song was last played, or the zero time if it wasn't.
+func lastPlayed(plays []*Play, s *Song) time.Time {
+	var last time.Time
+	for _, p := range plays {
+		if songKey(p.Song) == songKey(s) && p.PlayedAt.After(last) {
+			last = p.PlayedAt
+		}
+	}
+
+	return last
+}
+
+// streamsAgo returns how many streams ago the song was last played, or -1 if it wasn't
+// played. 0 is the open session's stream if there is one, even before anything's been
+// played on it, and otherwise the most recent stream in the plays.
+func streamsAgo(plays []*Play, open *Session, s *Song) int {
+	started := map[string]time.Time{}
+	if open != nil {
+		started[open.ID] = open.StartedAt
+	}
+	for _, p := range plays {
+		if at, ok := started[p.Stream]; !ok || p.PlayedAt.Before(at) {
+			started[p.Stream] = p.PlayedAt
+		}
+	}
+
+	streams := make([]string, 0, len(started))
+	for stream := range started {
+		streams = append(streams, stream)
+	}
+	slices.SortFunc(streams, func(a, b string) int { return started[b].Compare(started[a]) })
+
+	ago := -1
+	for _, p := range plays {
+		if songKey(p.Song) != songKey(s) {
+			continue
+		}
+		if i := slices.Index(streams, p.Stream); ago == -1 || i < ago {
+			ago = i
+		}
+	}
+
+	return ago
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCooldown(t *testing.T) {
+	now := time.Date(2024, 3, 9, 21, 0, 0, 0, time.UTC)
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	plays := []*Play{
+		{Song: &Song{Artist: "Trivium", Name: "Down From the Sky"}, Stream: "b", PlayedAt: now.Add(-30 * time.Minute)},
+		{Song: &Song{Artist: "sum 41", Name: "fat lip"}, Stream: "b", PlayedAt: now.Add(-90 * time.Minute)},
+		{Song: &Song{Artist: "Sum 41", Name: "Fat Lip"}, Stream: "a", PlayedAt: now.Add(-26 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name     string
+		cooldown *Cooldown
+		db       func(t *testing.T) *Mockdber
+		expected string
+	}{
+		{
+			name:     "refuses song played within minutes",
+			cooldown: &Cooldown{Minutes: 240},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("history", mock.Anything, historyQuery{Since: now.Add(-4 * time.Hour)}).Return(plays[:2], nil)
+
+				return db
+			},
+			expected: `"Fat Lip" by Sum 41 was played recently, it can be requested again in 2h30m0s`,
+		},
+		{
+			name:     "refuses song played within streams",
+			cooldown: &Cooldown{Streams: 2},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(nil, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: 2}).Return(plays, nil)
+
+				return db
+			},
+			expected: `"Fat Lip" by Sum 41 was played recently, it can be requested again in 2 streams`,
+		},
+		{
+			name:     "counts streams from the open session before anything's played on it",
+			cooldown: &Cooldown{Streams: 2},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(&Session{ID: "c", StartedAt: now.Add(-10 * time.Minute)}, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: 2}).Return(plays, nil)
+
+				return db
+			},
+			expected: `"Fat Lip" by Sum 41 was played recently, it can be requested again in 1 stream`,
+		},
+		{
+			name:     "combines both parts of cooldown",
+			cooldown: &Cooldown{Streams: 1, Minutes: 120},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("history", mock.Anything, historyQuery{Since: now.Add(-2 * time.Hour)}).Return(plays[:2], nil)
+				db.On("session", mock.Anything, "").Return(&Session{ID: "b", StartedAt: now.Add(-2 * time.Hour)}, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: 1}).Return(plays[:2], nil)
+
+				return db
+			},
+			expected: `"Fat Lip" by Sum 41 was played recently, it can be requested again in 30m0s and 1 stream`,
+		},
+		{
+			name:     "allows song played before cooldown",
+			cooldown: &Cooldown{Minutes: 60},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("history", mock.Anything, historyQuery{Since: now.Add(-time.Hour)}).Return(plays[:1], nil)
+
+				return db
+			},
+		},
+		{
+			name: "allows song without cooldown",
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := checkCooldown(context.Background(), tc.db(t), tc.cooldown, fatLip, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, reason)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errSetlistNotFound is returned when copying or merging a setlist that doesn't exist, or
+// merging into one that doesn't.
+var errSetlistNotFound = errors.New("setlist not found")
+
+// dedupePolicy determines what happens to songs that are in both setlists being merged.
+type dedupePolicy string
+
+const (
+	// dedupeNone appends every song, even those already in the setlist.
+	dedupeNone dedupePolicy = "none"
+	// dedupeFirst keeps songs where they first appear, leaving out later copies.
+	dedupeFirst dedupePolicy = "first"
+	// dedupeLast keeps songs where they last appear, so songs in both setlists move to
+	// where they are in the setlist merged in.
+	dedupeLast dedupePolicy = "last"
+)
+
+// copySetlist duplicates a setlist's songs, tags and target under a new name, failing with
+// errSetlistExists if a setlist already has that name.
+func copySetlist(ctx context.Context, db copier, name, to string) error {
+	sl, err := db.find(ctx, name)
+	if err != nil {
+		return fmt.Errorf("looking up setlist: %w", err)
+	}
+	if sl == nil {
+		return errSetlistNotFound
+	}
+
+	existing, err := db.find(ctx, to)
+	if err != nil {
+		return fmt.Errorf("looking up setlist to copy to: %w", err)
+	}
+	if existing != nil {
+		return errSetlistExists
+	}
+
+	if err := replace(ctx, db, to, sl.Songs); err != nil {
+		return err
+	}
+	if len(sl.Tags) > 0 {
+		if err := db.tag(ctx, to, sl.Tags); err != nil {
+			return fmt.Errorf("tagging setlist: %w", err)
+		}
+	}
+	if sl.Target > 0 {
+		if err := db.target(ctx, to, sl.Target, sl.TargetPolicy); err != nil {
+			return fmt.Errorf("setting setlist target: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mergeSetlists appends the songs of the setlist named from to the one named into,
+// handling songs in both according to the dedupe policy. The temporary setlist is created
+// if it doesn't exist yet, and the song being played at its top is never moved or removed.
+func mergeSetlists(ctx context.Context, db replacer, from, into string, policy dedupePolicy) error {
+	src, err := db.find(ctx, from)
+	if err != nil {
+		return fmt.Errorf("looking up setlist: %w", err)
+	}
+	dst, err := setlist(ctx, db, []byte(into))
+	if err != nil {
+		return fmt.Errorf("looking up setlist to merge into: %w", err)
+	}
+	if src == nil || dst == nil {
+		return errSetlistNotFound
+	}
+
+	if policy == dedupeNone {
+		for _, s := range src.Songs {
+			if err := db.add(ctx, into, s); err != nil {
+				return fmt.Errorf("adding song %q by %q: %w", s.Name, s.Artist, err)
+			}
+		}
+		return nil
+	}
+
+	var playing int
+	if into == tempSetlistName {
+		playing = min(len(dst.Songs), 1)
+	}
+
+	return replace(ctx, db, into, mergeSongs(dst.Songs, src.Songs, policy, playing))
+}
+
+// mergeSongs returns the songs of into followed by those of from, handling repeated songs
+// according to the dedupe policy. The first fixed songs of into are always kept where they
+// are, leaving out any later copies of them.
+func mergeSongs(into, from []*Song, policy dedupePolicy, fixed int) []*Song {
+	songs := append(append([]*Song{}, into...), from...)
+	if policy == dedupeNone {
+		return songs
+	}
+
+	last := map[string]int{}
+	for i, s := range songs {
+		last[songKey(s)] = i
+	}
+
+	merged := []*Song{}
+	seen := map[string]bool{}
+	for i, s := range songs {
+		k := songKey(s)
+		if i >= fixed && (seen[k] || policy == dedupeLast && last[k] != i) {
+			continue
+		}
+		seen[k] = true
+		merged = append(merged, s)
+	}
+
+	return merged
+}
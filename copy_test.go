@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeSongs(t *testing.T) {
+	a := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	b := &Song{Artist: "Sum 41", Name: "In Too Deep"}
+	c := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+
+	testCases := []struct {
+		name     string
+		policy   dedupePolicy
+		expected []*Song
+	}{
+		{name: "none", policy: dedupeNone, expected: []*Song{a, b, b, c}},
+		{name: "first", policy: dedupeFirst, expected: []*Song{a, b, c}},
+		{name: "last", policy: dedupeLast, expected: []*Song{a, b, c}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, mergeSongs([]*Song{a, b}, []*Song{b, c}, tc.policy, 0))
+		})
+	}
+
+	// last moves repeated songs to where they are in the setlist merged in
+	assert.Equal(t, []*Song{b, c, a}, mergeSongs([]*Song{a, b}, []*Song{c, a}, dedupeLast, 0))
+	// except for fixed songs, which stay put without being repeated
+	assert.Equal(t, []*Song{a, b, c}, mergeSongs([]*Song{a, b}, []*Song{c, a}, dedupeLast, 1))
+}
+
+func TestCopySetlist(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	chill := func() *Setlist {
+		return &Setlist{Name: "chill", Songs: []*Song{fatLip}, Tags: []string{"metal"}, Target: 3600, TargetPolicy: targetPolicyRefuse}
+	}
+
+	testCases := []struct {
+		name        string
+		db          func(t *testing.T) *Mockdber
+		expectedErr error
+	}{
+		{
+			name: "copies songs, tags and target",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(chill(), nil)
+				db.On("find", mock.Anything, "chill again").Return(nil, nil)
+				db.On("create", mock.Anything, "chill again").Return(&Setlist{Name: "chill again"}, nil)
+				db.On("add", mock.Anything, "chill again", fatLip).Return(nil)
+				db.On("tag", mock.Anything, "chill again", []string{"metal"}).Return(nil)
+				db.On("target", mock.Anything, "chill again", 3600, targetPolicyRefuse).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name: "refuses to copy over existing setlist",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(chill(), nil)
+				db.On("find", mock.Anything, "chill again").Return(&Setlist{Name: "chill again"}, nil)
+
+				return db
+			},
+			expectedErr: errSetlistExists,
+		},
+		{
+			name: "setlist not found",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(nil, nil)
+
+				return db
+			},
+			expectedErr: errSetlistNotFound,
+		},
+		{
+			name: "db returns error when looking up setlist",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(nil, fmt.Errorf("it broke"))
+
+				return db
+			},
+			expectedErr: fmt.Errorf("looking up setlist: it broke"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := copySetlist(context.Background(), tc.db(t), "chill", "chill again")
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMergeSetlist(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	downFromTheSky := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+
+	testCases := []struct {
+		name               string
+		query              string
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "queues setlist without repeats",
+			query: "?name=chill&dedupe=first",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{fatLip, downFromTheSky}}, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{fatLip}}, nil).Twice()
+				db.On("clear", mock.Anything, tempSetlistName).Return(nil)
+				db.On("add", mock.Anything, tempSetlistName, fatLip).Return(nil)
+				db.On("add", mock.Anything, tempSetlistName, downFromTheSky).Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{fatLip, downFromTheSky}}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"name":"temp","songs":[{"artist":"Sum 41","name":"Fat Lip"},` +
+				`{"artist":"Trivium","name":"Down From the Sky"}],"duration":30,"unknown_durations":2}`,
+		},
+		{
+			name:  "creates temporary setlist to queue setlist on",
+			query: "?name=chill",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{fatLip}}, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+				db.On("create", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName}, nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, fatLip).Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{fatLip}}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"name":"temp","songs":[{"artist":"Sum 41","name":"Fat Lip"}],"unknown_durations":1}`,
+		},
+		{
+			name:  "leaves song being played in place",
+			query: "?name=chill&dedupe=last",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{downFromTheSky, fatLip}}, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{fatLip}}, nil).Twice()
+				db.On("clear", mock.Anything, tempSetlistName).Return(nil)
+				playing := db.On("add", mock.Anything, tempSetlistName, fatLip).Return(nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, downFromTheSky).Return(nil).Once().NotBefore(playing)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{fatLip, downFromTheSky}}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"name":"temp","songs":[{"artist":"Sum 41","name":"Fat Lip"},` +
+				`{"artist":"Trivium","name":"Down From the Sky"}],"duration":30,"unknown_durations":2}`,
+		},
+		{
+			name:               "refuses merging into itself",
+			query:              "?name=chill&into=chill",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"a setlist can't be merged into itself"}`,
+		},
+		{
+			name:               "refuses unknown dedupe policy",
+			query:              "?name=chill&dedupe=all",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"dedupe must be one of none, first or last"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/setlist/merge" + tc.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
+
+func TestDiffSetlists(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	downFromTheSky := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+
+	db := NewMockdber(t)
+	db.On("find", mock.Anything, "last week").Return(&Setlist{Name: "last week", Songs: []*Song{fatLip, downFromTheSky}}, nil)
+	db.On("find", mock.Anything, "this week").Return(&Setlist{Name: "this week", Songs: []*Song{downFromTheSky, fatLip}}, nil)
+	db.On("find", mock.Anything, "next week").Return(nil, nil)
+
+	s := newServer()
+	s.db = db
+	client := newTestServer(t, routes(s).Handler)
+
+	resp, err := client.Get(lh + "/v1/setlist/diff?from=last%20week&to=this%20week")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"added":[],"removed":[],`+
+		`"moved":[{"song":{"artist":"Trivium","name":"Down From the Sky"},"from":2,"to":1}]}`, string(body))
+
+	resp, err = client.Get(lh + "/v1/setlist/diff?from=last%20week&to=next%20week")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, `{"error":"setlist \"next week\" not found"}`, string(body))
+}
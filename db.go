@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -18,9 +19,24 @@ type creator interface {
 	create(ctx context.Context, name string) (*Setlist, error)
 }
 
-// deleter provides the method delete, used to delete a setlist by name.
+// deleter provides the method delete, used to delete a setlist by name. Deleted setlists
+// are moved to the streamer's trash, where they're kept until expires.
 type deleter interface {
-	delete(ctx context.Context, name string) error
+	delete(ctx context.Context, name string, expires time.Time) error
+}
+
+// trasher provides the methods trashed, untrash & purge, used to restore setlists from the
+// streamer's trash and to empty it of setlists once they expire.
+type trasher interface {
+	// trashed returns the setlists in the trash that haven't expired, most recently deleted
+	// first.
+	trashed(ctx context.Context) ([]*TrashedSetlist, error)
+	// untrash moves the setlist with the provided trash ID back to the streamer's setlists
+	// under the provided name, returning nil if no such setlist is in the trash.
+	untrash(ctx context.Context, id, name string) (*Setlist, error)
+	// purge permanently removes the setlists that expired before the provided time from
+	// every streamer's trash.
+	purge(ctx context.Context, before time.Time) error
 }
 
 // clearer provides the method clear, used to clear a setlist by name.
@@ -40,14 +56,119 @@ type updater interface {
 	update(ctx context.Context, oldName, newName string) (*Setlist, error)
 }
 
-// songer provides the methods add & remove, which are used to modify a setlist's list of
-// songs.
+// lister provides the method list, used to find a streamer's persisted setlists. The
+// temporary setlist isn't listed.
+type lister interface {
+	// list returns a page of the setlists matching the query along with the total number
+	// of setlists that match.
+	list(ctx context.Context, q listQuery) ([]*SetlistListing, int, error)
+}
+
+// tagger provides the method tag, used to replace a setlist's tags.
+type tagger interface {
+	tag(ctx context.Context, name string, tags []string) error
+}
+
+// targeter provides the method target, used to set the length in seconds a setlist is
+// meant to fit in and what should happen when adding a song would go past it. A target of
+// zero removes the setlist's target.
+type targeter interface {
+	target(ctx context.Context, name string, target int, policy targetPolicy) error
+}
+
+// songer provides the methods add, remove & move, which are used to modify a setlist's
+// list of songs.
 type songer interface {
-	// add updates a setlist's song list, appending the provided artist & song.
-	add(ctx context.Context, setlistName, artistName, songName string) error
+	// add updates a setlist's song list, appending the provided song.
+	add(ctx context.Context, setlistName string, song *Song) error
 	// remove updates a setlist's song list, removing a song matching the provided name
 	// or position in the song list.
 	remove(ctx context.Context, setlistName, songName string, songNumber int) error
+	// move updates a setlist's song list, moving the song at the from position to the to
+	// position. Positions start at 1.
+	move(ctx context.Context, setlistName string, from, to int) error
+}
+
+// librarian provides the methods library & stock, used to look up and replace the charts
+// in a streamer's library.
+type librarian interface {
+	library(ctx context.Context) ([]*Chart, error)
+	// stock replaces the streamer's library with the provided charts.
+	stock(ctx context.Context, charts []*Chart) error
+}
+
+// historian provides the method history, used to look up the songs played on a streamer's
+// streams.
+type historian interface {
+	history(ctx context.Context, q historyQuery) ([]*Play, error)
+}
+
+// statistician provides the method stats, used to summarize a streamer's played history
+// in the database rather than loading every play and library chart.
+type statistician interface {
+	// stats summarizes the plays matching the query, listing up to limit entries in each
+	// of the top lists and of the library's songs that were never played.
+	stats(ctx context.Context, q historyQuery, limit int) (*Stats, error)
+}
+
+// blocker provides the methods blocklist & block, used to look up and replace the
+// streamer's blocklist.
+type blocker interface {
+	// blocklist returns the streamer's blocklist, or nil if they don't have one.
+	blocklist(ctx context.Context) (*Blocklist, error)
+	block(ctx context.Context, b *Blocklist) error
+}
+
+// sessioner provides the methods used to track a streamer's streams as sessions and record
+// the songs played during them.
+type sessioner interface {
+	// session returns the session with the provided ID, or the open session if the ID is
+	// empty. nil is returned if there's no such session.
+	session(ctx context.Context, id string) (*Session, error)
+	// sessions returns the streamer's sessions, most recent first.
+	sessions(ctx context.Context) ([]*Session, error)
+	// start opens a new session.
+	start(ctx context.Context, s *Session) error
+	// stop closes the open session, saving its end time and archived setlist.
+	stop(ctx context.Context, s *Session) error
+	// play records a song being played, which is then included in history.
+	play(ctx context.Context, p *Play) error
+}
+
+// scorer provides the methods record & scores, used to keep the streamer's scores on the
+// songs they play.
+type scorer interface {
+	record(ctx context.Context, sc *Score) error
+	// scores returns the scores on the song, matched by name and by artist when it's set.
+	scores(ctx context.Context, s *Song) ([]*Score, error)
+}
+
+// versioner provides the methods snapshot, versions & forget, used to keep earlier
+// versions of setlists so changes to them can be undone.
+type versioner interface {
+	// snapshot records a version, dropping the setlist's oldest versions past the most
+	// recent keep.
+	snapshot(ctx context.Context, v *Version, keep int) error
+	// versions returns a setlist's versions, most recent first.
+	versions(ctx context.Context, name string) ([]*Version, error)
+	// forget removes the setlist's version with the provided ID.
+	forget(ctx context.Context, name, id string) error
+}
+
+// reviewer provides the methods used to hold viewers' requests for review by a streamer's
+// mods before they're added to the temporary setlist.
+type reviewer interface {
+	// reviewing reports whether the streamer reviews requests.
+	reviewing(ctx context.Context) (bool, error)
+	// review turns reviewing requests on or off.
+	review(ctx context.Context, on bool) error
+	// pending returns the requests waiting for review, oldest first.
+	pending(ctx context.Context) ([]*PendingRequest, error)
+	// submit adds a request to those waiting for review.
+	submit(ctx context.Context, r *PendingRequest) error
+	// resolve removes the pending request with the provided ID, returning it or nil if no
+	// such request is pending.
+	resolve(ctx context.Context, id string) (*PendingRequest, error)
 }
 
 type finderCreator interface {
@@ -55,6 +176,93 @@ type finderCreator interface {
 	creator
 }
 
+// cooldowner combines the interfaces needed to check whether songs were played too
+// recently, counting streams from the open session.
+type cooldowner interface {
+	historian
+	sessioner
+}
+
+// restricter combines the interfaces needed to check songs against the streamer's blocklist
+// and replay cooldown.
+type restricter interface {
+	blocker
+	cooldowner
+}
+
+// songAdder combines the interfaces needed to add songs to a setlist with their details
+// filled in from the streamer's library.
+type songAdder interface {
+	finder
+	songer
+	librarian
+	restricter
+}
+
+// requester combines the interfaces needed to request songs for viewers, which may be held
+// for review.
+type requester interface {
+	songAdder
+	reviewer
+}
+
+// replacer combines the interfaces needed to create a setlist or overwrite an existing
+// setlist's songs.
+type replacer interface {
+	finder
+	creator
+	clearer
+	songer
+}
+
+// copier combines the interfaces needed to duplicate a setlist under a new name.
+type copier interface {
+	replacer
+	tagger
+	targeter
+}
+
+// archiver combines the interfaces needed to stop a session, archiving the songs played
+// during it as a setlist.
+type archiver interface {
+	sessioner
+	historian
+	replacer
+}
+
+// advancer combines the interfaces needed to move on from the song being played on the
+// temporary setlist, recording it as played.
+type advancer interface {
+	finder
+	songer
+	sessioner
+}
+
+// trashRestorer combines the interfaces needed to restore a setlist from the trash without
+// overwriting another setlist that has since taken its name.
+type trashRestorer interface {
+	finder
+	trasher
+}
+
+// undoer combines the interfaces needed to revert a setlist to its most recent version,
+// including restoring it from the trash if it was deleted or renaming it back if it was
+// renamed.
+type undoer interface {
+	versioner
+	replacer
+	updater
+	tagger
+	targeter
+	trasher
+}
+
+// scoreKeeper combines the interfaces needed to attach scores to played songs.
+type scoreKeeper interface {
+	scorer
+	historian
+}
+
 type dber interface {
 	finder
 	creator
@@ -63,10 +271,23 @@ type dber interface {
 	saver
 	updater
 	songer
+	librarian
+	targeter
+	historian
+	statistician
+	reviewer
+	blocker
+	sessioner
+	scorer
+	lister
+	tagger
+	versioner
+	trasher
 }
 
 // db represents the accesor to the server's database and implements the core interfaces
-// above.
+// above. Each streamer's data is kept in their own collection, determined from the
+// streamer a method's context is scoped to.
 type db struct {
 	client *mongo.Client
 }
@@ -86,7 +307,7 @@ func (db *db) create(ctx context.Context, name string) (*Setlist, error) {
 	return nil, nil
 }
 
-func (db *db) delete(ctx context.Context, name string) error {
+func (db *db) delete(ctx context.Context, name string, expires time.Time) error {
 	return nil
 }
 
@@ -102,10 +323,122 @@ func (db *db) update(ctx context.Context, oldName, newName string) (*Setlist, er
 	return nil, nil
 }
 
-func (db *db) add(ctx context.Context, setlistName, artistName, songName string) error {
+func (db *db) add(ctx context.Context, setlistName string, song *Song) error {
 	return nil
 }
 
 func (db *db) remove(ctx context.Context, setlistName, songName string, songNumber int) error {
 	return nil
 }
+
+func (db *db) move(ctx context.Context, setlistName string, from, to int) error {
+	return nil
+}
+
+func (db *db) library(ctx context.Context) ([]*Chart, error) {
+	return nil, nil
+}
+
+func (db *db) stock(ctx context.Context, charts []*Chart) error {
+	return nil
+}
+
+func (db *db) target(ctx context.Context, name string, target int, policy targetPolicy) error {
+	return nil
+}
+
+func (db *db) history(ctx context.Context, q historyQuery) ([]*Play, error) {
+	return nil, nil
+}
+
+func (db *db) stats(ctx context.Context, q historyQuery, limit int) (*Stats, error) {
+	return nil, nil
+}
+
+func (db *db) reviewing(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func (db *db) review(ctx context.Context, on bool) error {
+	return nil
+}
+
+func (db *db) pending(ctx context.Context) ([]*PendingRequest, error) {
+	return nil, nil
+}
+
+func (db *db) submit(ctx context.Context, r *PendingRequest) error {
+	return nil
+}
+
+func (db *db) resolve(ctx context.Context, id string) (*PendingRequest, error) {
+	return nil, nil
+}
+
+func (db *db) blocklist(ctx context.Context) (*Blocklist, error) {
+	return nil, nil
+}
+
+func (db *db) block(ctx context.Context, b *Blocklist) error {
+	return nil
+}
+
+func (db *db) session(ctx context.Context, id string) (*Session, error) {
+	return nil, nil
+}
+
+func (db *db) sessions(ctx context.Context) ([]*Session, error) {
+	return nil, nil
+}
+
+func (db *db) start(ctx context.Context, s *Session) error {
+	return nil
+}
+
+func (db *db) stop(ctx context.Context, s *Session) error {
+	return nil
+}
+
+func (db *db) play(ctx context.Context, p *Play) error {
+	return nil
+}
+
+func (db *db) record(ctx context.Context, sc *Score) error {
+	return nil
+}
+
+func (db *db) scores(ctx context.Context, s *Song) ([]*Score, error) {
+	return nil, nil
+}
+
+func (db *db) list(ctx context.Context, q listQuery) ([]*SetlistListing, int, error) {
+	return nil, 0, nil
+}
+
+func (db *db) tag(ctx context.Context, name string, tags []string) error {
+	return nil
+}
+
+func (db *db) snapshot(ctx context.Context, v *Version, keep int) error {
+	return nil
+}
+
+func (db *db) versions(ctx context.Context, name string) ([]*Version, error) {
+	return nil, nil
+}
+
+func (db *db) forget(ctx context.Context, name, id string) error {
+	return nil
+}
+
+func (db *db) trashed(ctx context.Context) ([]*TrashedSetlist, error) {
+	return nil, nil
+}
+
+func (db *db) untrash(ctx context.Context, id, name string) (*Setlist, error) {
+	return nil, nil
+}
+
+func (db *db) purge(ctx context.Context, before time.Time) error {
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+// SetlistDiff describes how one list of songs differs from another.
+type SetlistDiff struct {
+	Added   []*DiffSong `json:"added"`
+	Removed []*DiffSong `json:"removed"`
+	Moved   []*DiffMove `json:"moved"`
+}
+
+// DiffSong is a song added or removed at a position in a setlist, counting from 1.
+type DiffSong struct {
+	Song     *Song `json:"song"`
+	Position int   `json:"position"`
+}
+
+// DiffMove is a song that moved from one position in a setlist to another, counting
+// from 1.
+type DiffMove struct {
+	Song *Song `json:"song"`
+	From int   `json:"from"`
+	To   int   `json:"to"`
+}
+
+// diffSongs compares two lists of songs. A song in both lists is matched with itself, the
+// nth time it's in from with the nth time it's in to, and the rest are added or removed.
+// Matched songs that kept the most of their order relative to each other stay put, so only
+// the songs that were actually picked up and moved are listed as moved.
+func diffSongs(from, to []*Song) *SetlistDiff {
+	d := &SetlistDiff{Added: []*DiffSong{}, Removed: []*DiffSong{}, Moved: []*DiffMove{}}
+
+	positions := map[string][]int{}
+	for i, s := range to {
+		k := songKey(s)
+		positions[k] = append(positions[k], i)
+	}
+
+	matched := make([]bool, len(to))
+	// pairs holds the positions in from and to of each matched song, in the order of from.
+	var pairs [][2]int
+	for i, s := range from {
+		k := songKey(s)
+		if len(positions[k]) == 0 {
+			d.Removed = append(d.Removed, &DiffSong{Song: s, Position: i + 1})
+			continue
+		}
+		pairs = append(pairs, [2]int{i, positions[k][0]})
+		matched[positions[k][0]] = true
+		positions[k] = positions[k][1:]
+	}
+
+	for i, s := range to {
+		if !matched[i] {
+			d.Added = append(d.Added, &DiffSong{Song: s, Position: i + 1})
+		}
+	}
+
+	order := make([]int, len(pairs))
+	for i, p := range pairs {
+		order[i] = p[1]
+	}
+	kept := longestIncreasing(order)
+	for i, p := range pairs {
+		if !kept[i] {
+			d.Moved = append(d.Moved, &DiffMove{Song: to[p[1]], From: p[0] + 1, To: p[1] + 1})
+		}
+	}
+
+	return d
+}
+
+// longestIncreasing reports which of the values are part of their longest increasing
+// subsequence, preferring whichever ends first when there's more than one.
+func longestIncreasing(values []int) []bool {
+	length := make([]int, len(values))
+	prev := make([]int, len(values))
+	best := -1
+	for i := range values {
+		length[i], prev[i] = 1, -1
+		for j := range i {
+			if values[j] < values[i] && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if best == -1 || length[i] > length[best] {
+			best = i
+		}
+	}
+
+	in := make([]bool, len(values))
+	for i := best; i >= 0; i = prev[i] {
+		in[i] = true
+	}
+
+	return in
+}
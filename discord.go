@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// discordAPI is the base URL of Discord's HTTP API.
+	discordAPI = "https://discord.com/api/v10"
+
+	discordPing               = 1
+	discordApplicationCommand = 2
+
+	discordPong                     = 1
+	discordChannelMessageWithSource = 4
+
+	// discordEphemeral flags a response as only visible to the user who sent the command.
+	discordEphemeral = 1 << 6
+
+	// discordEmbedColor is the color of the bar along the side of embeds.
+	discordEmbedColor = 0x9146ff
+	// discordQueueLength is the number of songs listed in a queue embed, keeping it under
+	// Discord's description length limit.
+	discordQueueLength = 15
+)
+
+// discordCommands are the slash command definitions registered with Discord.
+var discordCommands = []*discordAppCommand{
+	{
+		Name:        "sr",
+		Description: "Request a song",
+		Options: []*discordAppCommandOption{
+			{Type: 3, Name: "artist", Description: "Artist of the song", Required: true},
+			{Type: 3, Name: "song", Description: "Name of the song", Required: true},
+		},
+	},
+	{Name: "queue", Description: "List the songs coming up"},
+	{Name: "nowplaying", Description: "Show the song being played"},
+}
+
+// discordAppCommand is an application command definition.
+type discordAppCommand struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Options     []*discordAppCommandOption `json:"options,omitempty"`
+}
+
+// discordAppCommandOption is an option of an application command. Type 3 is a string.
+type discordAppCommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// discordInteraction is an interaction sent to the interactions endpoint. Only the fields
+// used for song requests are included.
+type discordInteraction struct {
+	Type    int    `json:"type"`
+	GuildID string `json:"guild_id"`
+	Data    struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	// Member is set for interactions in a server and User for those in direct messages.
+	Member *struct {
+		User *discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+}
+
+type discordUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// option returns the string value of the named option, or an empty string if it wasn't
+// provided.
+func (i *discordInteraction) option(name string) string {
+	for _, o := range i.Data.Options {
+		if o.Name == name {
+			s, _ := o.Value.(string)
+			return s
+		}
+	}
+
+	return ""
+}
+
+// username returns the name of the user who sent the interaction.
+func (i *discordInteraction) username() string {
+	switch {
+	case i.Member != nil && i.Member.User != nil:
+		return i.Member.User.Username
+	case i.User != nil:
+		return i.User.Username
+	}
+
+	return ""
+}
+
+// discordResponse is the response to an interaction.
+type discordResponse struct {
+	Type int                  `json:"type"`
+	Data *discordResponseData `json:"data,omitempty"`
+}
+
+type discordResponseData struct {
+	Content string          `json:"content,omitempty"`
+	Embeds  []*discordEmbed `json:"embeds,omitempty"`
+	Flags   int             `json:"flags,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string               `json:"title,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Color       int                  `json:"color,omitempty"`
+	Fields      []*discordEmbedField `json:"fields,omitempty"`
+	Footer      *discordEmbedFooter  `json:"footer,omitempty"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordEmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// discordMessage returns a response with a plain message only visible to the user who
+// sent the command, used for replies like refusals.
+func discordMessage(content string) *discordResponse {
+	return &discordResponse{
+		Type: discordChannelMessageWithSource,
+		Data: &discordResponseData{Content: content, Flags: discordEphemeral},
+	}
+}
+
+// queueEmbed renders the songs coming up on a summarized temporary setlist.
+func queueEmbed(sl *Setlist) *discordEmbed {
+	e := &discordEmbed{Title: "Up Next", Color: discordEmbedColor}
+	if sl == nil || len(sl.Songs) < 2 {
+		e.Description = "The queue is empty"
+		return e
+	}
+
+	upNext := sl.Songs[1:]
+	var lines []string
+	for i, s := range upNext[:min(len(upNext), discordQueueLength)] {
+		line := fmt.Sprintf("%d. **%s** by %s", i+2, s.Name, s.Artist)
+		if s.RequestedBy != "" {
+			line += " (" + s.RequestedBy + ")"
+		}
+		lines = append(lines, line)
+	}
+	if more := len(upNext) - discordQueueLength; more > 0 {
+		lines = append(lines, fmt.Sprintf("…and %d more", more))
+	}
+	e.Description = strings.Join(lines, "\n")
+
+	footer := fmt.Sprintf("%d up next, about %s in total", len(upNext), time.Duration(sl.Duration)*time.Second)
+	if sl.UnknownDurations > 0 {
+		footer += fmt.Sprintf(" (%d of unknown length)", sl.UnknownDurations)
+	}
+	e.Footer = &discordEmbedFooter{Text: footer}
+
+	return e
+}
+
+// nowPlayingEmbed renders the song being played on a temporary setlist.
+func nowPlayingEmbed(sl *Setlist) *discordEmbed {
+	e := &discordEmbed{Title: "Now Playing", Color: discordEmbedColor}
+	if sl == nil || len(sl.Songs) == 0 {
+		e.Description = "Nothing is playing right now"
+		return e
+	}
+
+	s := sl.Songs[0]
+	e.Description = fmt.Sprintf("**%s** by %s", s.Name, s.Artist)
+	for _, f := range []struct{ name, value string }{
+		{"Album", s.Album},
+		{"Charter", s.Charter},
+		{"Requested By", s.RequestedBy},
+	} {
+		if f.value != "" {
+			e.Fields = append(e.Fields, &discordEmbedField{Name: f.name, Value: f.value, Inline: true})
+		}
+	}
+	if s.Year != 0 {
+		e.Fields = append(e.Fields, &discordEmbedField{Name: "Year", Value: fmt.Sprint(s.Year), Inline: true})
+	}
+
+	return e
+}
+
+// parseDiscordGuilds parses guild to streamer mappings in the form used by the
+// -discord-guilds flag: comma separated guildID=streamer pairs.
+func parseDiscordGuilds(s string) (map[string]string, error) {
+	guilds := map[string]string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+
+		guild, streamer, ok := strings.Cut(p, "=")
+		if !ok || guild == "" || streamer == "" {
+			return nil, fmt.Errorf("%q must be a guild ID and streamer, e.g. 1234=doomedfingers", p)
+		}
+		guilds[guild] = streamer
+	}
+
+	return guilds, nil
+}
+
+// verifyDiscord reports whether the hex encoded Ed25519 signature of the timestamp and body
+// was made with the application's public key.
+func verifyDiscord(key ed25519.PublicKey, signature, timestamp string, body []byte) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+
+	return ed25519.Verify(key, append([]byte(timestamp), body...), sig)
+}
+
+// registerDiscordCommands registers discordCommands for the application, replacing any
+// commands already registered. Commands are registered with the guild when one is provided
+// since those take effect immediately, otherwise globally.
+func registerDiscordCommands(client *http.Client, api, appID, guildID, token string) error {
+	b, err := json.Marshal(discordCommands)
+	if err != nil {
+		return fmt.Errorf("marshalling commands: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/applications/%s/commands", api, appID)
+	if guildID != "" {
+		u = fmt.Sprintf("%s/applications/%s/guilds/%s/commands", api, appID, guildID)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
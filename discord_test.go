@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscordInteractions(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+	queued := &Setlist{
+		Name: tempSetlistName,
+		Songs: []*Song{
+			{Artist: "DragonForce", Name: "Through the Fire and Flames", Album: "Inhuman Rampage", Year: 2006, Duration: 441},
+			{Artist: "Trivium", Name: "Down From the Sky", Duration: 334, RequestedBy: "other_user"},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		payload string
+		// signKey signs the payload instead of the application's key when set.
+		signKey            ed25519.PrivateKey
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "answers pings",
+			payload:            "ping.json",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"type":1}`,
+		},
+		{
+			name:    "requests song and replies with it as found in the library",
+			payload: "sr.json",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				song := &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180, RequestedBy: "cooler_user"}
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{{Song: Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}}}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName}, nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, requested(song)).Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name:  tempSetlistName,
+					Songs: []*Song{{Artist: "Trivium", Name: "Down From the Sky", Duration: 334}, song},
+				}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"type":4,"data":{` +
+				`"content":"cooler_user requested **Fat Lip** by Sum 41",` +
+				`"embeds":[{"title":"Up Next","description":"2. **Fat Lip** by Sum 41 (cooler_user)","color":9520895,` +
+				`"footer":{"text":"1 up next, about 9m4s in total"}}]` +
+				`}}`,
+		},
+		{
+			name:    "lists queue",
+			payload: "queue.json",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"type":4,"data":{"embeds":[{` +
+				`"title":"Up Next","description":"2. **Down From the Sky** by Trivium (other_user)","color":9520895,` +
+				`"footer":{"text":"1 up next, about 13m25s in total"}}]` +
+				`}}`,
+		},
+		{
+			name:    "shows song being played",
+			payload: "nowplaying.json",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"type":4,"data":{"embeds":[{` +
+				`"title":"Now Playing","description":"**Through the Fire and Flames** by DragonForce","color":9520895,` +
+				`"fields":[{"name":"Album","value":"Inhuman Rampage","inline":true},{"name":"Year","value":"2006","inline":true}]}]` +
+				`}}`,
+		},
+		{
+			name:               "tells servers that aren't linked to a streamer",
+			payload:            "unlinked_guild.json",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"type":4,"data":{"content":"This server isn't linked to a streamer.","flags":64}}`,
+		},
+		{
+			name:               "refuses requests with a bad signature",
+			payload:            "sr.json",
+			signKey:            ed25519.NewKeyFromSeed(bytes.Repeat([]byte{8}, ed25519.SeedSize)),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       `{"error":"invalid request signature"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "discord", tc.payload))
+			require.NoError(t, err)
+
+			s := newServer()
+			s.db = NewMockdber(t)
+			if tc.db != nil {
+				s.db = tc.db(t)
+			}
+			s.discordKey = key.Public().(ed25519.PublicKey)
+			s.discordGuilds = map[string]string{"1083437452830711858": "doomedfingers"}
+			client := newTestServer(t, routes(s).Handler)
+
+			signKey := key
+			if tc.signKey != nil {
+				signKey = tc.signKey
+			}
+			timestamp := "1698080000"
+			sig := ed25519.Sign(signKey, append([]byte(timestamp), body...))
+
+			req, err := http.NewRequest(http.MethodPost, lh+"/webhooks/discord/interactions", bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(sig))
+			req.Header.Set("X-Signature-Timestamp", timestamp)
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(respBody))
+		})
+	}
+}
+
+func TestRegisterDiscordCommands(t *testing.T) {
+	var registered []*discordAppCommand
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/applications/app/guilds/guild/commands", r.URL.Path)
+		assert.Equal(t, "Bot token", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&registered))
+		w.Write([]byte("[]")) // nolint: errcheck
+	}))
+	defer api.Close()
+
+	require.NoError(t, registerDiscordCommands(api.Client(), api.URL, "app", "guild", "token"))
+
+	var names []string
+	for _, c := range registered {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"sr", "queue", "nowplaying"}, names)
+}
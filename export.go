@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// exporter describes a format setlists can be exported to.
+type exporter struct {
+	contentType string
+	extension   string
+	write       func(w io.Writer, sl *Setlist) error
+}
+
+// exporters contains each supported export format by the name used to request it.
+var exporters = map[string]exporter{
+	"csv":      {contentType: "text/csv; charset=utf-8", extension: "csv", write: exportCSV},
+	"markdown": {contentType: "text/markdown; charset=utf-8", extension: "md", write: exportMarkdown},
+	"m3u":      {contentType: "audio/x-mpegurl; charset=utf-8", extension: "m3u", write: exportM3U},
+	"text":     {contentType: "text/plain; charset=utf-8", extension: "txt", write: exportText},
+}
+
+// exportCSV writes a setlist as CSV with a header row followed by one row per song.
+func exportCSV(w io.Writer, sl *Setlist) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"position", "artist", "song"}); err != nil {
+		return err
+	}
+	for i, s := range sl.Songs {
+		if err := cw.Write([]string{strconv.Itoa(i + 1), csvCell(s.Artist), csvCell(s.Name)}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFormulaPrefixes are the characters spreadsheets treat as the start of a formula.
+const csvFormulaPrefixes = "=+-@"
+
+// csvCell returns s escaped for a CSV cell. Artists and song names come from viewers, so
+// cells that a spreadsheet would evaluate as a formula are prefixed with a quote.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+
+	return s
+}
+
+// markdownEscaper escapes characters that would otherwise be treated as markdown syntax or
+// break out of a table cell.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// exportMarkdown writes a setlist as a heading followed by a table of its songs.
+func exportMarkdown(w io.Writer, sl *Setlist) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(sl.Name))
+	b.WriteString("| # | Artist | Song |\n")
+	b.WriteString("| -: | --- | --- |\n")
+	for i, s := range sl.Songs {
+		fmt.Fprintf(&b, "| %d | %s | %s |\n", i+1, markdownEscaper.Replace(s.Artist), markdownEscaper.Replace(s.Name))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportM3U writes a setlist as an extended M3U playlist. Since songs aren't tied to
+// files, each entry's location is its display title so the playlist still lists every
+// song when opened.
+func exportM3U(w io.Writer, sl *Setlist) error {
+	var b strings.Builder
+
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#PLAYLIST:%s\n", singleLine(sl.Name))
+	for _, s := range sl.Songs {
+		title := singleLine(s.Artist) + " - " + singleLine(s.Name)
+		fmt.Fprintf(&b, "#EXTINF:-1,%s\n", title)
+
+		// lines starting with # are directives or comments, not locations
+		if strings.HasPrefix(title, "#") {
+			title = "./" + title
+		}
+		fmt.Fprintf(&b, "%s\n", title)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportText writes a setlist as its name followed by a numbered list of its songs.
+func exportText(w io.Writer, sl *Setlist) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n\n", singleLine(sl.Name))
+	for i, s := range sl.Songs {
+		fmt.Fprintf(&b, "%d. %s - %s\n", i+1, singleLine(s.Artist), singleLine(s.Name))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// lineBreakReplacer replaces line breaks with spaces for line based formats.
+var lineBreakReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+// singleLine returns s with any line breaks replaced by spaces.
+func singleLine(s string) string {
+	return lineBreakReplacer.Replace(s)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestExporters(t *testing.T) {
+	sl := &Setlist{
+		Name: "Doomed Fingers | Vol. 1",
+		Songs: []*Song{
+			{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+			{Artist: "Between the Buried and Me", Name: `Ants of the Sky, "Live"`},
+			{Artist: "Sum 41", Name: "Fat_Lip *Remastered*"},
+			{Artist: "#1 Band", Name: "Multi\nLine | Song"},
+			{Artist: "@Formula", Name: `=HYPERLINK("http://example.com","Song")`},
+		},
+	}
+
+	for format, e := range exporters {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			require.NoError(t, e.write(&buf, sl))
+
+			golden := filepath.Join("testdata", "export", format+".golden")
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
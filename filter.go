@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// defaultFilterInstrument is the instrument difficulty filters apply to when none is
+// provided.
+const defaultFilterInstrument = "guitar"
+
+// songFilter describes criteria songs can be matched against. Text fields match
+// case-insensitively and zero values are treated as not set.
+type songFilter struct {
+	Artist  string
+	Album   string
+	Genre   string
+	Charter string
+	Source  string
+	// Decade matches songs released in the decade starting with the provided year, e.g.
+	// 1990.
+	Decade int
+	// Instrument is the instrument MinDifficulty and MaxDifficulty apply to.
+	Instrument    string
+	MinDifficulty *int
+	MaxDifficulty *int
+	// MaxDuration matches songs no longer than the provided number of seconds.
+	MaxDuration int
+}
+
+// parseSongFilter builds a filter from request arguments of the same names in snake case.
+func parseSongFilter(args *fasthttp.Args) (*songFilter, error) {
+	f := &songFilter{
+		Artist:     string(args.Peek("artist")),
+		Album:      string(args.Peek("album")),
+		Genre:      string(args.Peek("genre")),
+		Charter:    string(args.Peek("charter")),
+		Source:     string(args.Peek("source")),
+		Instrument: string(args.Peek("instrument")),
+	}
+	if f.Instrument == "" {
+		f.Instrument = defaultFilterInstrument
+	}
+
+	for _, n := range []struct {
+		arg string
+		dst *int
+	}{
+		{"decade", &f.Decade},
+		{"max_duration", &f.MaxDuration},
+	} {
+		if !args.Has(n.arg) {
+			continue
+		}
+
+		v, err := strconv.Atoi(string(args.Peek(n.arg)))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", n.arg)
+		}
+		*n.dst = v
+	}
+
+	for _, n := range []struct {
+		arg string
+		dst **int
+	}{
+		{"min_difficulty", &f.MinDifficulty},
+		{"max_difficulty", &f.MaxDifficulty},
+	} {
+		if !args.Has(n.arg) {
+			continue
+		}
+
+		v, err := strconv.Atoi(string(args.Peek(n.arg)))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", n.arg)
+		}
+		*n.dst = &v
+	}
+
+	return f, nil
+}
+
+// match reports whether the song meets all of the filter's criteria.
+func (f *songFilter) match(s *Song) bool {
+	for _, t := range []struct{ want, have string }{
+		{f.Artist, s.Artist},
+		{f.Album, s.Album},
+		{f.Genre, s.Genre},
+		{f.Charter, s.Charter},
+		{f.Source, s.Source},
+	} {
+		if t.want != "" && !strings.EqualFold(strings.TrimSpace(t.want), strings.TrimSpace(t.have)) {
+			return false
+		}
+	}
+
+	if f.Decade != 0 && (s.Year < f.Decade || s.Year >= f.Decade+10) {
+		return false
+	}
+	if f.MaxDuration != 0 && s.Duration > f.MaxDuration {
+		return false
+	}
+
+	if f.MinDifficulty != nil || f.MaxDifficulty != nil {
+		d, ok := s.Difficulties[f.Instrument]
+		if !ok {
+			return false
+		}
+		if f.MinDifficulty != nil && d < *f.MinDifficulty {
+			return false
+		}
+		if f.MaxDifficulty != nil && d > *f.MaxDifficulty {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSongFilterMatch(t *testing.T) {
+	song := &Song{
+		Artist:       "DragonForce",
+		Name:         "Through the Fire and Flames",
+		Genre:        "Power Metal",
+		Year:         2006,
+		Charter:      "Harmonix",
+		Duration:     441,
+		Difficulties: map[string]int{"guitar": 6, "drums": 5},
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{name: "matches without criteria", query: "", expected: true},
+		{name: "matches text fields case-insensitively", query: "genre=power%20metal&artist=dragonforce", expected: true},
+		{name: "does not match different genre", query: "genre=Djent", expected: false},
+		{name: "matches decade", query: "decade=2000", expected: true},
+		{name: "does not match other decade", query: "decade=1990", expected: false},
+		{name: "matches guitar difficulty range by default", query: "min_difficulty=5&max_difficulty=6", expected: true},
+		{name: "does not match difficulty above max", query: "instrument=drums&max_difficulty=4", expected: false},
+		{name: "does not match uncharted instrument", query: "instrument=keys&max_difficulty=6", expected: false},
+		{name: "does not match songs that are too long", query: "max_duration=300", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := fasthttp.AcquireArgs()
+			defer fasthttp.ReleaseArgs(args)
+			args.Parse(tc.query)
+
+			f, err := parseSongFilter(args)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, f.match(song))
+		})
+	}
+}
+
+func TestParseSongFilterErrors(t *testing.T) {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+	args.Parse("max_difficulty=hard")
+
+	_, err := parseSongFilter(args)
+
+	assert.EqualError(t, err, "max_difficulty must be a number")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// gameExporter describes a rhythm game setlist format. Since games identify charts by a
+// hash, chartHash returns the hash used by the game for a chart or an empty string if the
+// chart doesn't have one.
+type gameExporter struct {
+	contentType string
+	extension   string
+	chartHash   func(c *Chart) string
+	write       func(w io.Writer, sl *Setlist, charts []*Chart) error
+}
+
+// gameExporters contains each supported game format by the name used to request it.
+var gameExporters = map[string]gameExporter{
+	"clonehero": {
+		contentType: "application/octet-stream",
+		extension:   "setlist",
+		chartHash:   func(c *Chart) string { return c.MD5 },
+		write:       exportCloneHero,
+	},
+	"yarg": {
+		contentType: "application/json",
+		extension:   "json",
+		chartHash:   func(c *Chart) string { return c.SHA1 },
+		write:       exportYARG,
+	},
+}
+
+// gameCharts matches the setlist's songs against the library, returning the charts to
+// export in setlist order, songs that have no chart and songs whose chart lacks the hash
+// the game requires, e.g. charts read from a songcache when exporting to YARG.
+func (g gameExporter) gameCharts(sl *Setlist, library []*Chart) ([]*Chart, []*Song, []*Song) {
+	matched, unmatched := matchCharts(sl, library)
+
+	charts := make([]*Chart, 0, len(matched))
+	var skipped []*Song
+	for _, c := range matched {
+		if g.chartHash(c) == "" {
+			skipped = append(skipped, &Song{Artist: c.Artist, Name: c.Name})
+			continue
+		}
+		charts = append(charts, c)
+	}
+
+	return charts, unmatched, skipped
+}
+
+// exportCloneHero writes charts as a Clone Hero .setlist file: each chart's MD5 checksum
+// in order, written as a .NET BinaryWriter string (a 7-bit encoded length followed by the
+// string's UTF-8 bytes).
+func exportCloneHero(w io.Writer, sl *Setlist, charts []*Chart) error {
+	var b []byte
+	for _, c := range charts {
+		b = binary.AppendUvarint(b, uint64(len(c.MD5)))
+		b = append(b, c.MD5...)
+	}
+
+	_, err := w.Write(b)
+	return err
+}
+
+// yargPlaylist is the shape of a YARG playlist file.
+type yargPlaylist struct {
+	Name       string   `json:"Name"`
+	Author     string   `json:"Author"`
+	ID         string   `json:"Id"`
+	SongHashes []string `json:"SongHashes"`
+}
+
+// exportYARG writes charts as a YARG playlist. YARG stores song hashes as base64 encoded
+// SHA-1 hashes. The playlist's ID is derived from the setlist's name so exporting the same
+// setlist again replaces the playlist rather than adding a copy.
+func exportYARG(w io.Writer, sl *Setlist, charts []*Chart) error {
+	p := yargPlaylist{
+		Name:       sl.Name,
+		Author:     "songvoyage",
+		ID:         nameUUID(sl.Name),
+		SongHashes: make([]string, 0, len(charts)),
+	}
+	for _, c := range charts {
+		h, err := hex.DecodeString(c.SHA1)
+		if err != nil {
+			return fmt.Errorf("decoding hash for %q by %q: %w", c.Name, c.Artist, err)
+		}
+		p.SongHashes = append(p.SongHashes, base64.StdEncoding.EncodeToString(h))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// nameUUID returns a UUID derived from the MD5 hash of the provided name, laid out as a
+// version 3 UUID.
+func nameUUID(name string) string {
+	h := md5.Sum([]byte(name))
+	h[6] = h[6]&0x0f | 0x30
+	h[8] = h[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
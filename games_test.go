@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGameExporters(t *testing.T) {
+	sl := &Setlist{
+		Name: "Doomed Fingers",
+		Songs: []*Song{
+			{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+			{Artist: "Sum 41", Name: "Fat Lip"},
+			{Artist: "Trivium", Name: "Down From the Sky"},
+		},
+	}
+	library := []*Chart{
+		{
+			Song: Song{Artist: "DragonForce", Name: "Through The Fire And Flames"},
+			MD5:  "0123456789abcdef0123456789abcdef",
+			SHA1: "0123456789abcdef0123456789abcdef01234567",
+		},
+		{
+			Song: Song{Artist: "Trivium", Name: "Down From the Sky"},
+			MD5:  "fedcba9876543210fedcba9876543210",
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		game              string
+		expectedUnmatched []*Song
+		expectedSkipped   []*Song
+	}{
+		{
+			name: "clonehero",
+			game: "clonehero",
+			expectedUnmatched: []*Song{
+				{Artist: "Sum 41", Name: "Fat Lip"},
+			},
+		},
+		{
+			name: "yarg",
+			game: "yarg",
+			expectedUnmatched: []*Song{
+				{Artist: "Sum 41", Name: "Fat Lip"},
+			},
+			expectedSkipped: []*Song{
+				{Artist: "Trivium", Name: "Down From the Sky"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			g := gameExporters[tc.game]
+
+			charts, unmatched, skipped := g.gameCharts(sl, library)
+			require.NoError(t, g.write(&buf, sl, charts))
+
+			golden := filepath.Join("testdata", "games", tc.game+"."+g.extension)
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, expected, buf.Bytes())
+			assert.Equal(t, tc.expectedUnmatched, unmatched)
+			assert.Equal(t, tc.expectedSkipped, skipped)
+		})
+	}
+}
+
+func TestExportGameSetlist(t *testing.T) {
+	library := []*Chart{
+		{Song: Song{Artist: "Sum 41", Name: "Fat Lip"}, MD5: "0123456789abcdef0123456789abcdef"},
+	}
+
+	testCases := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "exports charts with the game's hash",
+			query:              "?name=chill&game=clonehero",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "\x200123456789abcdef0123456789abcdef",
+		},
+		{
+			name:               "reports charts without the game's hash as skipped",
+			query:              "?name=chill&game=yarg",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"some songs have no matching chart in the library or no hash for the game",` +
+				`"skipped":[{"artist":"Sum 41","name":"Fat Lip"}]}`,
+		},
+		{
+			name:               "refuses partial export with nothing to export",
+			query:              "?name=chill&game=yarg&partial=true",
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"error":"some songs have no matching chart in the library or no hash for the game",` +
+				`"skipped":[{"artist":"Sum 41","name":"Fat Lip"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := NewMockdber(t)
+			db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{{Artist: "Sum 41", Name: "Fat Lip"}}}, nil)
+			db.On("library", mock.Anything).Return(library, nil)
+
+			s := newServer()
+			s.db = db
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/setlist/export/game" + tc.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
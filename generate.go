@@ -0,0 +1,89 @@
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+)
+
+// defaultGenerateCount is the number of songs generated when neither a count nor a target
+// length is requested.
+const defaultGenerateCount = 20
+
+// generateRequest describes the songs a generated setlist should be made up of.
+type generateRequest struct {
+	Filter *songFilter
+	// NotPlayedIn excludes songs played in the streamer's most recent number of streams.
+	NotPlayedIn int
+	// Target is the length in seconds the setlist should fit in. When set, songs without a
+	// known duration are left out since they can't be fit.
+	Target int
+	// Count limits the number of songs picked.
+	Count int
+	// Gap is the number of seconds expected between songs.
+	Gap int
+	// Seed seeds the random choice of songs so a setlist can be generated again.
+	Seed uint64
+}
+
+// generate picks songs from the library matching the request. Given the same library,
+// history and request, the same songs are picked in the same order.
+func generate(library []*Chart, played []*Play, r *generateRequest) []*Song {
+	recent := playedSongs(played)
+
+	var candidates []*Song
+	for _, c := range library {
+		s := &c.Song
+		if !r.Filter.match(s) || recent[songKey(s)] {
+			continue
+		}
+		if r.Target > 0 && s.Duration == 0 {
+			continue
+		}
+		candidates = append(candidates, s)
+	}
+
+	// libraries aren't guaranteed to be returned in the same order, so sort before
+	// shuffling to keep generation reproducible
+	slices.SortStableFunc(candidates, func(a, b *Song) int {
+		return cmp.Compare(songKey(a), songKey(b))
+	})
+	rnd := rand.New(rand.NewPCG(r.Seed, r.Seed))
+	rnd.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	count := r.Count
+	if count == 0 && r.Target == 0 {
+		count = defaultGenerateCount
+	}
+
+	var (
+		songs []*Song
+		total int
+		seen  = map[string]bool{}
+	)
+	for _, s := range candidates {
+		if count > 0 && len(songs) == count {
+			break
+		}
+		if seen[songKey(s)] {
+			continue
+		}
+
+		length := s.Duration
+		if len(songs) > 0 {
+			length += r.Gap
+		}
+		if r.Target > 0 && total+length > r.Target {
+			continue
+		}
+
+		details := *s
+		songs = append(songs, &details)
+		seen[songKey(s)] = true
+		total += length
+	}
+
+	return songs
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	library := []*Chart{
+		{Song: Song{Artist: "DragonForce", Name: "Through the Fire and Flames", Genre: "Metal", Year: 2006, Duration: 441}},
+		{Song: Song{Artist: "Trivium", Name: "Down From the Sky", Genre: "Metal", Year: 2008, Duration: 334}},
+		{Song: Song{Artist: "Sum 41", Name: "Fat Lip", Genre: "Punk", Year: 2001, Duration: 180}},
+		{Song: Song{Artist: "Avenged Sevenfold", Name: "Afterlife", Genre: "Metal", Year: 2007, Duration: 352}},
+		{Song: Song{Artist: "Megadeth", Name: "Hangar 18", Genre: "Metal", Year: 1990}},
+		{Song: Song{Artist: "Slayer", Name: "Raining Blood", Genre: "Metal", Year: 1986, Duration: 254}},
+	}
+
+	testCases := []struct {
+		name     string
+		played   []*Play
+		request  *generateRequest
+		expected []string
+	}{
+		{
+			name:     "picks filtered songs up to count",
+			request:  &generateRequest{Filter: &songFilter{Genre: "metal", Decade: 2000}, Count: 2, Seed: 1},
+			expected: []string{"Afterlife", "Through the Fire and Flames"},
+		},
+		{
+			name: "leaves out recently played songs",
+			played: []*Play{
+				{Song: &Song{Artist: "Avenged Sevenfold", Name: "Afterlife"}},
+			},
+			request:  &generateRequest{Filter: &songFilter{Genre: "metal", Decade: 2000}, Count: 2, Seed: 1},
+			expected: []string{"Down From the Sky", "Through the Fire and Flames"},
+		},
+		{
+			name:     "fits songs with known durations in target",
+			request:  &generateRequest{Filter: &songFilter{}, Target: 900, Gap: 30, Seed: 7},
+			expected: []string{"Afterlife", "Through the Fire and Flames"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			songs := generate(library, tc.played, tc.request)
+
+			var actual []string
+			for _, s := range songs {
+				actual = append(actual, s.Name)
+			}
+			assert.Equal(t, tc.expected, actual)
+
+			// generating again with the same seed and a reordered library gives the same songs
+			reversed := make([]*Chart, len(library))
+			for i, c := range library {
+				reversed[len(library)-1-i] = c
+			}
+			assert.Equal(t, songs, generate(reversed, tc.played, tc.request))
+		})
+	}
+}
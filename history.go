@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Play records a song being played during one of a streamer's streams.
+type Play struct {
+	Song *Song `json:"song"`
+	// Stream identifies the stream the song was played on.
+	Stream   string    `json:"stream"`
+	PlayedAt time.Time `json:"played_at"`
+}
+
+// historyQuery narrows down the plays looked up from a streamer's history. Zero values are
+// treated as not set.
+type historyQuery struct {
+	// Streams limits plays to those from the streamer's most recent number of streams.
+	Streams int
+	// Stream limits plays to those from the stream with the ID.
+	Stream string
+	Since  time.Time
+	Until  time.Time
+}
+
+// playedSongs returns the keys of the songs played, as returned by songKey.
+func playedSongs(plays []*Play) map[string]bool {
+	played := make(map[string]bool, len(plays))
+	for _, p := range plays {
+		played[songKey(p.Song)] = true
+	}
+
+	return played
+}
+
+// parseHistoryQuery parses the streams, since and until query parameters narrowing down a
+// streamer's history. since and until take either a date, e.g. 2024-03-09, or a time in
+// RFC 3339 format. A date for until includes the whole day.
+func parseHistoryQuery(args *fasthttp.Args) (historyQuery, error) {
+	var q historyQuery
+	if args.Has("streams") {
+		var err error
+		if q.Streams, err = args.GetUint("streams"); err != nil {
+			return q, fmt.Errorf("streams must be a whole number")
+		}
+	}
+
+	for _, t := range []struct {
+		arg string
+		dst *time.Time
+		// day is added to dates to include the whole day
+		day bool
+	}{
+		{"since", &q.Since, false},
+		{"until", &q.Until, true},
+	} {
+		v := string(args.Peek(t.arg))
+		if v == "" {
+			continue
+		}
+
+		if d, err := time.Parse(time.DateOnly, v); err == nil {
+			if t.day {
+				d = d.AddDate(0, 0, 1)
+			}
+			*t.dst = d
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, fmt.Errorf("%s must be a date like 2024-03-09 or an RFC 3339 time", t.arg)
+		}
+		*t.dst = at
+	}
+
+	return q, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// errUnknownImportFormat is returned when an import is requested in an unsupported format.
+var errUnknownImportFormat = errors.New("unknown import format")
+
+// importReport describes the outcome of parsing an import, line by line.
+type importReport struct {
+	Setlist    string        `json:"setlist"`
+	Parsed     []*importLine `json:"parsed"`
+	Skipped    []*importLine `json:"skipped"`
+	Duplicates []*importLine `json:"duplicates"`
+}
+
+// importLine represents a single line of an import. For JSON imports, Line is the song's
+// 1-based position in the list of songs.
+type importLine struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text,omitempty"`
+	Song   *Song  `json:"song,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// songs returns the songs that were successfully parsed.
+func (r *importReport) songs() []*Song {
+	songs := make([]*Song, 0, len(r.Parsed))
+	for _, l := range r.Parsed {
+		songs = append(songs, l.Song)
+	}
+
+	return songs
+}
+
+// parseImport parses data in the provided format: csv, json or text. If no format is
+// provided, it will be detected from the data. Songs already seen earlier in the data are
+// reported as duplicates rather than parsed.
+func parseImport(format string, data []byte) (*importReport, error) {
+	if format == "" {
+		format = detectImportFormat(data)
+	}
+
+	r := &importReport{
+		Parsed:     []*importLine{},
+		Skipped:    []*importLine{},
+		Duplicates: []*importLine{},
+	}
+
+	var err error
+	switch format {
+	case "csv":
+		err = parseCSVImport(r, data)
+	case "json":
+		err = parseJSONImport(r, data)
+	case "text":
+		err = parseTextImport(r, data)
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnknownImportFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", format, err)
+	}
+
+	return r, nil
+}
+
+// detectImportFormat guesses the format of data. JSON is assumed when it looks like an
+// object, CSV when the first line has commas but no "Artist - Song" separator and plain
+// text otherwise.
+func detectImportFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return "json"
+	}
+
+	first, _, _ := bytes.Cut(trimmed, []byte("\n"))
+	if bytes.Contains(first, []byte(",")) && !bytes.Contains(first, []byte(" - ")) {
+		return "csv"
+	}
+
+	return "text"
+}
+
+// add records a parsed song, flagging it as a duplicate if it has already been parsed.
+func (r *importReport) add(line int, text string, s *Song) {
+	s.Artist = strings.TrimSpace(s.Artist)
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Artist == "" || s.Name == "" {
+		r.Skipped = append(r.Skipped, &importLine{Line: line, Text: text, Reason: "missing artist or song"})
+		return
+	}
+
+	for _, p := range r.Parsed {
+		if songKey(p.Song) == songKey(s) {
+			r.Duplicates = append(r.Duplicates, &importLine{
+				Line:   line,
+				Text:   text,
+				Song:   s,
+				Reason: fmt.Sprintf("duplicate of line %d", p.Line),
+			})
+			return
+		}
+	}
+
+	r.Parsed = append(r.Parsed, &importLine{Line: line, Text: text, Song: s})
+}
+
+// listNumbering matches list markers such as "1.", "2)" or "-" at the start of a line.
+var listNumbering = regexp.MustCompile(`^(\d+[.)]|[-*])\s+`)
+
+// parseTextImport parses "Artist - Song" lines, ignoring blank lines and lines starting
+// with #. Leading list markers are removed so exported text setlists can be re-imported,
+// and the setlist name heading a text export is recorded on the report.
+func parseTextImport(r *importReport, data []byte) error {
+	name, heading := textExportName(data)
+	if heading {
+		r.Setlist = name
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") || heading && line == 1 {
+			continue
+		}
+
+		artist, song, ok := strings.Cut(listNumbering.ReplaceAllString(text, ""), " - ")
+		if !ok {
+			r.Skipped = append(r.Skipped, &importLine{Line: line, Text: text, Reason: `expected "Artist - Song"`})
+			continue
+		}
+
+		r.add(line, text, &Song{Artist: artist, Name: song})
+	}
+
+	return sc.Err()
+}
+
+// textExportName returns the setlist name heading data written by exportText: the name,
+// a blank line and then the numbered list of songs.
+func textExportName(data []byte) (string, bool) {
+	lines := strings.SplitN(string(data), "\n", 4)
+	if len(lines) < 3 || strings.TrimSpace(lines[1]) != "" || !strings.HasPrefix(lines[2], "1. ") {
+		return "", false
+	}
+
+	name := strings.TrimSpace(lines[0])
+	return name, name != "" && !listNumbering.MatchString(name)
+}
+
+// parseCSVImport parses CSV rows. If the first row is a header containing an artist
+// column and a song or name column those columns are used, otherwise the first two
+// columns are treated as the artist and song.
+func parseCSVImport(r *importReport, data []byte) error {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	artistCol, songCol := 0, 1
+	for row := 0; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		text := strings.Join(record, ",")
+
+		if row == 0 {
+			if a, s, ok := csvHeader(record); ok {
+				artistCol, songCol = a, s
+				continue
+			}
+		}
+
+		if len(record) <= max(artistCol, songCol) {
+			r.Skipped = append(r.Skipped, &importLine{Line: line, Text: text, Reason: "missing columns"})
+			continue
+		}
+
+		r.add(line, text, &Song{Artist: unescapeCSVCell(record[artistCol]), Name: unescapeCSVCell(record[songCol])})
+	}
+}
+
+// unescapeCSVCell reverses csvCell, so setlists exported as CSV import with the same
+// artists and song names.
+func unescapeCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+
+	return s
+}
+
+// csvHeader returns the artist and song column indexes if record is a header row.
+func csvHeader(record []string) (artist, song int, ok bool) {
+	artist, song = -1, -1
+	for i, col := range record {
+		switch strings.ToLower(strings.TrimSpace(col)) {
+		case "artist":
+			artist = i
+		case "song", "name", "title":
+			if song == -1 {
+				song = i
+			}
+		}
+	}
+
+	return artist, song, artist != -1 && song != -1
+}
+
+// parseJSONImport parses data in the same shape as a Setlist response. The setlist's name,
+// if present, is recorded on the report. Songs' priorities and requesters are dropped, so
+// re-importing an export doesn't put old requests ahead of new ones.
+func parseJSONImport(r *importReport, data []byte) error {
+	var sl Setlist
+	if err := json.Unmarshal(data, &sl); err != nil {
+		return err
+	}
+
+	r.Setlist = sl.Name
+	for i, s := range sl.Songs {
+		if s == nil {
+			r.Skipped = append(r.Skipped, &importLine{Line: i + 1, Reason: "missing artist or song"})
+			continue
+		}
+
+		// requests belong to the stream they were made on, so they aren't brought along
+		s.Priority, s.RequestedBy, s.RequestedAt = 0, "", nil
+		r.add(i+1, "", s)
+	}
+
+	return nil
+}
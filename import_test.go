@@ -0,0 +1,167 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImport(t *testing.T) {
+	testCases := []struct {
+		name        string
+		format      string
+		data        string
+		expected    *importReport
+		expectedErr string
+	}{
+		{
+			name:   "text - parses lines, skipping comments and flagging duplicates",
+			format: "text",
+			data: "# warmup\n" +
+				"1. Dragonforce - Through the Fire and Flames\n" +
+				"\n" +
+				"Just a song name\n" +
+				"2) dragonforce - through the fire and flames\n" +
+				"- Sum 41 - Fat Lip\n",
+			expected: &importReport{
+				Parsed: []*importLine{
+					{
+						Line: 2,
+						Text: "1. Dragonforce - Through the Fire and Flames",
+						Song: &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+					},
+					{
+						Line: 6,
+						Text: "- Sum 41 - Fat Lip",
+						Song: &Song{Artist: "Sum 41", Name: "Fat Lip"},
+					},
+				},
+				Skipped: []*importLine{
+					{Line: 4, Text: "Just a song name", Reason: `expected "Artist - Song"`},
+				},
+				Duplicates: []*importLine{
+					{
+						Line:   5,
+						Text:   "2) dragonforce - through the fire and flames",
+						Song:   &Song{Artist: "dragonforce", Name: "through the fire and flames"},
+						Reason: "duplicate of line 2",
+					},
+				},
+			},
+		},
+		{
+			name:   "text - records setlist name heading an export",
+			format: "text",
+			data:   "Doomed Fingers - Vol. 1\n\n1. Sum 41 - Fat Lip\n",
+			expected: &importReport{
+				Setlist: "Doomed Fingers - Vol. 1",
+				Parsed: []*importLine{
+					{
+						Line: 3,
+						Text: "1. Sum 41 - Fat Lip",
+						Song: &Song{Artist: "Sum 41", Name: "Fat Lip"},
+					},
+				},
+				Skipped:    []*importLine{},
+				Duplicates: []*importLine{},
+			},
+		},
+		{
+			name: "csv - detected and parsed using header columns",
+			data: "position,song,artist\n" +
+				"1,\"Ants of the Sky, Live\",Between the Buried and Me\n" +
+				"2,Fat Lip\n" +
+				"3,,Sum 41\n",
+			expected: &importReport{
+				Parsed: []*importLine{
+					{
+						Line: 2,
+						Text: "1,Ants of the Sky, Live,Between the Buried and Me",
+						Song: &Song{Artist: "Between the Buried and Me", Name: "Ants of the Sky, Live"},
+					},
+				},
+				Skipped: []*importLine{
+					{Line: 3, Text: "2,Fat Lip", Reason: "missing columns"},
+					{Line: 4, Text: "3,,Sum 41", Reason: "missing artist or song"},
+				},
+				Duplicates: []*importLine{},
+			},
+		},
+		{
+			name:   "csv - first columns used without header",
+			format: "csv",
+			data:   "Dragonforce,Through the Fire and Flames\n",
+			expected: &importReport{
+				Parsed: []*importLine{
+					{
+						Line: 1,
+						Text: "Dragonforce,Through the Fire and Flames",
+						Song: &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+					},
+				},
+				Skipped:    []*importLine{},
+				Duplicates: []*importLine{},
+			},
+		},
+		{
+			name:   "csv - unescapes formula cells escaped by export",
+			format: "csv",
+			data:   "position,artist,song\n1,'@Formula,'=Sum\n",
+			expected: &importReport{
+				Parsed: []*importLine{
+					{
+						Line: 2,
+						Text: "1,'@Formula,'=Sum",
+						Song: &Song{Artist: "@Formula", Name: "=Sum"},
+					},
+				},
+				Skipped:    []*importLine{},
+				Duplicates: []*importLine{},
+			},
+		},
+		{
+			name: "json - detected and parsed from setlist shape, dropping requests",
+			data: `{"name":"Doomed Fingers","songs":[` +
+				`{"artist":"Dragonforce","name":"Through the Fire and Flames","priority":2,` +
+				`"requested_by":"cooler_user","requested_at":"2024-03-12T20:15:00Z"},` +
+				`{"artist":"","name":"Mystery"}` +
+				`]}`,
+			expected: &importReport{
+				Setlist: "Doomed Fingers",
+				Parsed: []*importLine{
+					{
+						Line: 1,
+						Song: &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+					},
+				},
+				Skipped:    []*importLine{{Line: 2, Reason: "missing artist or song"}},
+				Duplicates: []*importLine{},
+			},
+		},
+		{
+			name:        "json - errors on malformed data",
+			format:      "json",
+			data:        `{"name":`,
+			expectedErr: "parsing json: unexpected end of JSON input",
+		},
+		{
+			name:        "errors on unknown format",
+			format:      "xml",
+			expectedErr: `unknown import format: "xml"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseImport(tc.format, []byte(tc.data))
+
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ircRateLimit and ircRateWindow limit the chat messages sent, matching Twitch's limit
+	// for accounts that aren't a channel's moderator.
+	ircRateLimit  = 20
+	ircRateWindow = 30 * time.Second
+
+	ircMinBackoff = time.Second
+	ircMaxBackoff = 2 * time.Minute
+
+	// ircQueueLength is the number of upcoming songs listed by !queue.
+	ircQueueLength = 5
+	// ircQueueSize is the number of commands and replies that can wait to be handled or
+	// sent before more are dropped.
+	ircQueueSize = 32
+)
+
+// ircLineBreaks replaces line breaks in outgoing lines, which would otherwise end the line
+// early and let the rest be read as another command, e.g. from a song's name in a reply.
+var ircLineBreaks = strings.NewReplacer("\r", " ", "\n", " ")
+
+// ircMessage is a line received from an IRC server.
+type ircMessage struct {
+	// Tags holds the message's tags, if any were sent. Values aren't unescaped.
+	Tags map[string]string
+	// Nick is the nickname of the message's sender, if the message has a prefix.
+	Nick    string
+	Command string
+	// Params holds the command's parameters, including the trailing parameter.
+	Params []string
+}
+
+// parseIRC parses a line received from an IRC server.
+func parseIRC(line string) *ircMessage {
+	m := &ircMessage{}
+	line = strings.TrimRight(line, "\r\n")
+
+	if strings.HasPrefix(line, "@") {
+		var tags string
+		tags, line, _ = strings.Cut(line[1:], " ")
+		m.Tags = map[string]string{}
+		for _, t := range strings.Split(tags, ";") {
+			k, v, _ := strings.Cut(t, "=")
+			m.Tags[k] = v
+		}
+	}
+	if strings.HasPrefix(line, ":") {
+		var prefix string
+		prefix, line, _ = strings.Cut(line[1:], " ")
+		m.Nick, _, _ = strings.Cut(prefix, "!")
+	}
+
+	line, trailing, hasTrailing := strings.Cut(line, " :")
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		m.Command, m.Params = fields[0], fields[1:]
+	}
+	if hasTrailing {
+		m.Params = append(m.Params, trailing)
+	}
+
+	return m
+}
+
+// moderator reports whether the message was sent by the channel's broadcaster or one of its
+// moderators, going by the tags Twitch sends.
+func (m *ircMessage) moderator() bool {
+	if m.Tags["mod"] == "1" {
+		return true
+	}
+	for _, b := range strings.Split(m.Tags["badges"], ",") {
+		if strings.HasPrefix(b, "broadcaster/") || strings.HasPrefix(b, "moderator/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rateLimiter limits actions to a number within a sliding window of time.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	recent []time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window}
+}
+
+// wait blocks until another action is allowed or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		for len(l.recent) > 0 && now.Sub(l.recent[0]) >= l.window {
+			l.recent = l.recent[1:]
+		}
+		if len(l.recent) < l.limit {
+			l.recent = append(l.recent, now)
+			l.mu.Unlock()
+			return nil
+		}
+		delay := l.window - now.Sub(l.recent[0])
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ircBot is a chat bot that connects to an IRC server, e.g. Twitch chat, and handles song
+// request commands in the channels it joins. Each channel is scoped to the streamer of the
+// same name.
+type ircBot struct {
+	s        *server
+	nick     string
+	pass     string
+	channels []string
+	// dial connects to the IRC server.
+	dial func(ctx context.Context) (net.Conn, error)
+	// limiter limits outgoing chat messages so the bot isn't dropped for spamming.
+	limiter    *rateLimiter
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// mu guards replies, which queues lines to send while the bot is connected.
+	mu      sync.Mutex
+	replies chan<- string
+}
+
+// newIRCBot creates a bot that connects to the IRC server at addr, over TLS if useTLS is
+// set, and joins the provided channels.
+func newIRCBot(s *server, addr string, useTLS bool, nick, pass string, channels []string) *ircBot {
+	return &ircBot{
+		s:        s,
+		nick:     nick,
+		pass:     pass,
+		channels: channels,
+		dial: func(ctx context.Context) (net.Conn, error) {
+			if useTLS {
+				return (&tls.Dialer{}).DialContext(ctx, "tcp", addr)
+			}
+			return (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		},
+		limiter:    newRateLimiter(ircRateLimit, ircRateWindow),
+		minBackoff: ircMinBackoff,
+		maxBackoff: ircMaxBackoff,
+	}
+}
+
+// run keeps the bot connected until ctx is done, reconnecting with an exponential backoff
+// whenever the connection is lost.
+func (b *ircBot) run(ctx context.Context) {
+	backoff := b.minBackoff
+	for {
+		connected, err := b.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = b.minBackoff
+		}
+
+		log.Printf("irc - disconnected: %s, reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, b.maxBackoff)
+	}
+}
+
+// session connects to the server and handles messages until the connection is lost,
+// reporting whether it connected at all.
+func (b *ircBot) session(ctx context.Context) (bool, error) {
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var mu sync.Mutex
+	write := func(line string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		_, err := io.WriteString(conn, ircLineBreaks.Replace(line)+"\r\n")
+		return err
+	}
+
+	// tags identify the streamer and their mods, who can override the replay cooldown
+	if err := write("CAP REQ :twitch.tv/tags"); err != nil {
+		return true, err
+	}
+	if b.pass != "" {
+		if err := write("PASS " + b.pass); err != nil {
+			return true, err
+		}
+	}
+	if err := write("NICK " + b.nick); err != nil {
+		return true, err
+	}
+	for _, c := range b.channels {
+		if err := write("JOIN #" + strings.TrimPrefix(c, "#")); err != nil {
+			return true, err
+		}
+	}
+
+	// replies are sent separately so waiting on the rate limit doesn't hold up reading
+	replies := make(chan string, ircQueueSize)
+	sent := make(chan struct{})
+	sctx, cancel := context.WithCancel(ctx)
+	b.setReplies(replies)
+	go func() {
+		defer close(sent)
+		for line := range replies {
+			if b.limiter.wait(sctx) != nil {
+				continue
+			}
+			if err := write(line); err != nil {
+				log.Printf("irc - sending reply: %s", err)
+			}
+		}
+	}()
+
+	// commands are handled separately too, so slow lookups don't hold up answering PINGs
+	commands := make(chan *ircMessage, ircQueueSize)
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		for m := range commands {
+			channel := m.Params[0]
+			cctx := withStreamer(sctx, strings.TrimPrefix(channel, "#"))
+			reply := b.command(cctx, m.Nick, m.moderator(), m.Params[1])
+			if reply == "" {
+				continue
+			}
+			if err := b.say(channel, reply); err != nil {
+				log.Printf("irc - replying in %s: %s", channel, err)
+			}
+		}
+	}()
+
+	defer func() {
+		cancel()
+		close(commands)
+		<-handled
+		b.setReplies(nil)
+		close(replies)
+		<-sent
+	}()
+
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		m := parseIRC(sc.Text())
+		switch m.Command {
+		case "PING":
+			if err := write("PONG :" + strings.Join(m.Params, " ")); err != nil {
+				return true, err
+			}
+		case "PRIVMSG":
+			if len(m.Params) < 2 || !strings.HasPrefix(strings.TrimSpace(m.Params[1]), "!") {
+				continue
+			}
+			select {
+			case commands <- m:
+			default:
+				log.Printf("irc - dropping %q from %s: too many commands waiting", m.Params[1], m.Nick)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return true, err
+	}
+
+	return true, io.EOF
+}
+
+func (b *ircBot) setReplies(replies chan<- string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.replies = replies
+}
+
+// say queues a message to be sent to the channel, failing if the bot isn't connected or
+// too many messages are already waiting to be sent. Line breaks in the message are sent as
+// spaces.
+func (b *ircBot) say(channel, msg string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.replies == nil {
+		return errors.New("not connected")
+	}
+	select {
+	case b.replies <- ircLineBreaks.Replace(fmt.Sprintf("PRIVMSG %s :%s", channel, msg)):
+		return nil
+	default:
+		return errors.New("too many messages waiting to be sent")
+	}
+}
+
+// announce sends a message to the channel of the streamer ctx is scoped to.
+func (b *ircBot) announce(ctx context.Context, msg string) error {
+	return b.say("#"+streamerFrom(ctx), msg)
+}
+
+// command handles a chat message from the user, returning the bot's reply or an empty
+// string if the message isn't a command. mod is whether the user is the streamer or one
+// of their mods. ctx must be scoped to the channel's streamer.
+func (b *ircBot) command(ctx context.Context, user string, mod bool, text string) string {
+	ctx, cancel := context.WithTimeout(withChange(ctx, ""), 5*time.Second)
+	defer cancel()
+
+	name, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	var (
+		reply string
+		err   error
+	)
+	switch strings.ToLower(name) {
+	case "!sr":
+		reply, err = b.requestSong(ctx, user, mod, args)
+	case "!queue":
+		reply, err = b.queue(ctx)
+	case "!song":
+		reply, err = b.nowPlaying(ctx)
+	case "!wrongsong":
+		reply, err = b.wrongSong(ctx, user)
+	case "!position":
+		reply, err = b.position(ctx, user)
+	case "!pb":
+		reply, err = b.personalBest(ctx, args)
+	case "!vote":
+		reply, err = b.vote(ctx, user, args)
+	default:
+		return ""
+	}
+
+	var refusal *refusalError
+	switch {
+	case errors.As(err, &refusal):
+		return fmt.Sprintf("@%s %s", user, refusal.reason)
+	case err != nil:
+		log.Printf("irc - %s from %s: %s", name, user, err)
+		return fmt.Sprintf("@%s something went wrong, try again later", user)
+	}
+
+	return reply
+}
+
+// requestSong handles !sr, requesting a song on the temporary setlist. The streamer and
+// their mods can end a request with --override to skip the replay cooldown. Warnings about
+// the request, e.g. going over the setlist's target length, are added to the reply.
+func (b *ircBot) requestSong(ctx context.Context, user string, mod bool, input string) (string, error) {
+	input, override := strings.CutSuffix(strings.TrimSpace(input), " --override")
+	artist, song, ok := parseSongInput(input)
+	if !ok {
+		return fmt.Sprintf("@%s request songs with !sr Artist - Song", user), nil
+	}
+
+	added, p, warnings, err := requestSong(ctx, b.s.db, &songRequest{
+		Artist:   artist,
+		Song:     song,
+		Gap:      b.s.songGap,
+		User:     user,
+		Trusted:  mod,
+		Override: override && mod,
+	})
+	if err != nil {
+		return "", err
+	}
+	if p != nil {
+		return fmt.Sprintf("@%s your request for %s is waiting for approval", user, chatSong(p.Song)), nil
+	}
+
+	sl, err := b.s.refresh(ctx, "irc request song", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var notes string
+	if len(warnings) > 0 {
+		notes = " (" + strings.Join(warnings, ", ") + ")"
+	}
+	if sl != nil {
+		// the request is the user's most recent song with a matching name
+		key := songKey(added)
+		for i := len(sl.Songs) - 1; i >= 0; i-- {
+			if s := sl.Songs[i]; s.RequestedBy == user && songKey(s) == key {
+				return fmt.Sprintf("@%s added %s at #%d%s", user, chatSong(s), i+1, notes), nil
+			}
+		}
+	}
+
+	return fmt.Sprintf("@%s added %s%s", user, chatSong(added), notes), nil
+}
+
+// vote handles !vote, voting for a candidate in the streamer's open poll by its number.
+func (b *ircBot) vote(ctx context.Context, user, input string) (string, error) {
+	choice, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(input), "#"))
+	if err != nil {
+		return fmt.Sprintf("@%s vote with !vote and the song's number", user), nil
+	}
+
+	p, err := b.s.polls.vote(streamerFrom(ctx), user, choice)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("@%s voted for %s", user, chatSong(p.Candidates[choice-1])), nil
+}
+
+// queue handles !queue, listing the songs coming up after the one being played.
+func (b *ircBot) queue(ctx context.Context) (string, error) {
+	sl, err := b.s.db.find(ctx, tempSetlistName)
+	if err != nil {
+		return "", err
+	}
+	if sl == nil || len(sl.Songs) < 2 {
+		return "The queue is empty", nil
+	}
+
+	upNext := sl.Songs[1:]
+	var songs []string
+	for i, s := range upNext[:min(len(upNext), ircQueueLength)] {
+		songs = append(songs, fmt.Sprintf("#%d %s", i+2, chatSong(s)))
+	}
+	if more := len(upNext) - ircQueueLength; more > 0 {
+		songs = append(songs, fmt.Sprintf("and %d more", more))
+	}
+
+	return "Up next: " + strings.Join(songs, " | "), nil
+}
+
+// nowPlaying handles !song, naming the song being played.
+func (b *ircBot) nowPlaying(ctx context.Context) (string, error) {
+	sl, err := b.s.db.find(ctx, tempSetlistName)
+	if err != nil {
+		return "", err
+	}
+	if sl == nil || len(sl.Songs) == 0 {
+		return "Nothing is playing right now", nil
+	}
+
+	return "Now playing: " + chatSong(sl.Songs[0]), nil
+}
+
+// personalBest handles !pb, looking up the streamer's best scores on a song, or on the
+// song being played if none is named.
+func (b *ircBot) personalBest(ctx context.Context, input string) (string, error) {
+	var song *Song
+	if strings.TrimSpace(input) != "" {
+		song = parseScoreSong(input)
+	} else {
+		sl, err := b.s.db.find(ctx, tempSetlistName)
+		if err != nil {
+			return "", err
+		}
+		if sl == nil || len(sl.Songs) == 0 {
+			return "Look up a PB with !pb Artist - Song", nil
+		}
+		song = sl.Songs[0]
+	}
+
+	scores, err := b.s.db.scores(ctx, song)
+	if err != nil {
+		return "", err
+	}
+
+	return pbMessage(song, personalBests(scores)), nil
+}
+
+// wrongSong handles !wrongsong, removing the user's most recent request that isn't being
+// played.
+func (b *ircBot) wrongSong(ctx context.Context, user string) (string, error) {
+	sl, err := b.s.db.find(ctx, tempSetlistName)
+	if err != nil {
+		return "", err
+	}
+
+	if sl != nil {
+		for i := len(sl.Songs) - 1; i > 0; i-- {
+			if s := sl.Songs[i]; s.RequestedBy == user {
+				if err := removeSong(ctx, b.s.db, nil, nil, i+1); err != nil {
+					return "", err
+				}
+				if _, err := b.s.refresh(ctx, "irc wrong song", nil); err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf("@%s removed %s", user, chatSong(s)), nil
+			}
+		}
+	}
+
+	return fmt.Sprintf("@%s you have no songs in the queue", user), nil
+}
+
+// position handles !position, listing where the user's requests are in the queue.
+func (b *ircBot) position(ctx context.Context, user string) (string, error) {
+	sl, err := b.s.db.find(ctx, tempSetlistName)
+	if err != nil {
+		return "", err
+	}
+
+	var songs []string
+	if sl != nil {
+		for i, s := range sl.Songs {
+			if s.RequestedBy != user {
+				continue
+			}
+			if i == 0 {
+				songs = append(songs, fmt.Sprintf("%s is playing now", chatSong(s)))
+				continue
+			}
+			songs = append(songs, fmt.Sprintf("%s is #%d", chatSong(s), i+1))
+		}
+	}
+	if len(songs) == 0 {
+		return fmt.Sprintf("@%s you have no songs in the queue", user), nil
+	}
+
+	return fmt.Sprintf("@%s %s", user, strings.Join(songs, ", ")), nil
+}
+
+// chatSong formats a song for chat messages, in the same form songs are requested in.
+func chatSong(s *Song) string {
+	return s.Artist + " - " + s.Name
+}
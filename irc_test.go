@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIRC(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected *ircMessage
+	}{
+		{
+			line:     "PING :tmi.twitch.tv\r\n",
+			expected: &ircMessage{Command: "PING", Params: []string{"tmi.twitch.tv"}},
+		},
+		{
+			line: ":cooler_user!cooler_user@cooler_user.tmi.twitch.tv PRIVMSG #doomedfingers :!sr Sum 41 - Fat Lip",
+			expected: &ircMessage{
+				Nick:    "cooler_user",
+				Command: "PRIVMSG",
+				Params:  []string{"#doomedfingers", "!sr Sum 41 - Fat Lip"},
+			},
+		},
+		{
+			line: "@badge-info=;badges=moderator/1;color=#FF0000 :cooler_user!cooler_user@cooler_user.tmi.twitch.tv PRIVMSG #doomedfingers :!song",
+			expected: &ircMessage{
+				Tags:    map[string]string{"badge-info": "", "badges": "moderator/1", "color": "#FF0000"},
+				Nick:    "cooler_user",
+				Command: "PRIVMSG",
+				Params:  []string{"#doomedfingers", "!song"},
+			},
+		},
+		{
+			line:     ":tmi.twitch.tv 001 songvoyage :Welcome, GLHF!",
+			expected: &ircMessage{Nick: "tmi.twitch.tv", Command: "001", Params: []string{"songvoyage", "Welcome, GLHF!"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseIRC(tc.line))
+		})
+	}
+}
+
+func TestIRCMessageModerator(t *testing.T) {
+	for line, expected := range map[string]bool{
+		"@badges=broadcaster/1,subscriber/0 :doomedfingers PRIVMSG #doomedfingers :!sr":  true,
+		"@badges=moderator/1;mod=1 :cooler_user PRIVMSG #doomedfingers :!sr":             true,
+		"@badges=subscriber/12;mod=0 :cooler_user PRIVMSG #doomedfingers :!sr":           false,
+		":cooler_user!cooler_user@cooler_user.tmi.twitch.tv PRIVMSG #doomedfingers :!sr": false,
+	} {
+		assert.Equal(t, expected, parseIRC(line).moderator(), line)
+	}
+}
+
+func TestIRCBotCommand(t *testing.T) {
+	queued := func() *Setlist {
+		return &Setlist{
+			Name: tempSetlistName,
+			Songs: []*Song{
+				{Artist: "DragonForce", Name: "Through the Fire and Flames", RequestedBy: "cooler_user"},
+				{Artist: "Trivium", Name: "Down From the Sky", RequestedBy: "other_user"},
+				{Artist: "Sum 41", Name: "Fat Lip", RequestedBy: "cooler_user"},
+			},
+		}
+	}
+
+	cooldown := &Blocklist{Cooldown: &Cooldown{Streams: 3}}
+
+	testCases := []struct {
+		name     string
+		text     string
+		mod      bool
+		db       func(t *testing.T) *Mockdber
+		expected string
+	}{
+		{
+			name: "requests song",
+			text: "!sr Slayer - Raining Blood",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				song := &Song{Artist: "Slayer", Name: "Raining Blood", RequestedBy: "cooler_user"}
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(queued(), nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, requested(song)).Return(nil)
+				sl := queued()
+				sl.Songs = append(sl.Songs, song)
+				db.On("find", mock.Anything, tempSetlistName).Return(sl, nil)
+
+				return db
+			},
+			expected: "@cooler_user added Slayer - Raining Blood at #4",
+		},
+		{
+			name: "relays warnings about request",
+			text: "!sr Slayer - Raining Blood",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				song := &Song{Artist: "Slayer", Name: "Raining Blood", RequestedBy: "cooler_user"}
+				targeted := queued()
+				targeted.Target = 60
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(targeted, nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, requested(song)).Return(nil)
+				sl := queued()
+				sl.Songs = append(sl.Songs, song)
+				db.On("find", mock.Anything, tempSetlistName).Return(sl, nil)
+
+				return db
+			},
+			expected: `@cooler_user added Slayer - Raining Blood at #4 (adding "Raining Blood" would put "temp" 30s over its target length)`,
+		},
+		{
+			name: "requests song when setlist can't be looked up after",
+			text: "!sr Slayer - Raining Blood",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+				db.On("add", mock.Anything, tempSetlistName, mock.Anything).Return(nil)
+				db.On("create", mock.Anything, tempSetlistName).Return(nil, nil)
+
+				return db
+			},
+			expected: "@cooler_user added Slayer - Raining Blood",
+		},
+		{
+			name: "holds request for review",
+			text: "!sr Slayer - Raining Blood",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(true, nil)
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("submit", mock.Anything, mock.Anything).Return(nil)
+
+				return db
+			},
+			expected: "@cooler_user your request for Slayer - Raining Blood is waiting for approval",
+		},
+		{
+			name: "explains how to request songs",
+			text: "!sr raining blood",
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expected: "@cooler_user request songs with !sr Artist - Song",
+		},
+		{
+			name: "relays refusals",
+			text: "!sr Slayer - Raining Blood",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				sl := queued()
+				sl.Target, sl.TargetPolicy = 60, targetPolicyRefuse
+				sl.Songs[0].Duration = 60
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{
+					{Song: Song{Artist: "Slayer", Name: "Raining Blood", Duration: 254}},
+				}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(sl, nil)
+
+				return db
+			},
+			expected: `@cooler_user adding "Raining Blood" would put "temp" 5m44s over its target length`,
+		},
+		{
+			name: "refuses songs on cooldown",
+			text: "!sr Slayer - Raining Blood --override",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(cooldown, nil)
+				db.On("session", mock.Anything, "").Return(&Session{ID: "a"}, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: 3}).Return([]*Play{
+					{Song: &Song{Artist: "Slayer", Name: "Raining Blood"}, Stream: "a", PlayedAt: time.Now().Add(-time.Hour)},
+				}, nil)
+
+				return db
+			},
+			expected: `@cooler_user "Raining Blood" by Slayer was played recently, it can be requested again in 3 streams`,
+		},
+		{
+			name: "lets mods override cooldown and review",
+			text: "!sr Slayer - Raining Blood --override",
+			mod:  true,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				song := &Song{Artist: "Slayer", Name: "Raining Blood", RequestedBy: "cooler_user"}
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(cooldown, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, requested(song)).Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{song}}, nil)
+
+				return db
+			},
+			expected: "@cooler_user added Slayer - Raining Blood at #1",
+		},
+		{
+			name: "lists queue",
+			text: "!queue",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued(), nil)
+
+				return db
+			},
+			expected: "Up next: #2 Trivium - Down From the Sky | #3 Sum 41 - Fat Lip",
+		},
+		{
+			name: "names song being played",
+			text: "!song",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued(), nil)
+
+				return db
+			},
+			expected: "Now playing: DragonForce - Through the Fire and Flames",
+		},
+		{
+			name: "removes user's latest request",
+			text: "!wrongsong",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued(), nil)
+				db.On("remove", mock.Anything, tempSetlistName, "", 3).Return(nil)
+
+				return db
+			},
+			expected: "@cooler_user removed Sum 41 - Fat Lip",
+		},
+		{
+			name: "lists user's positions",
+			text: "!position",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued(), nil)
+
+				return db
+			},
+			expected: "@cooler_user DragonForce - Through the Fire and Flames is playing now, Sum 41 - Fat Lip is #3",
+		},
+		{
+			name: "looks up personal best on song being played",
+			text: "!pb",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(queued(), nil)
+				db.On("scores", mock.Anything, queued().Songs[0]).Return([]*Score{{
+					Song:       &Song{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+					Score:      250000,
+					Accuracy:   95,
+					Stars:      5,
+					Instrument: "guitar",
+					Difficulty: "expert",
+				}}, nil)
+
+				return db
+			},
+			expected: "PB on DragonForce - Through the Fire and Flames: expert guitar 250000 (95.00%, 5★)",
+		},
+		{
+			name: "reports db failures",
+			text: "!song",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, fmt.Errorf("it broke"))
+
+				return db
+			},
+			expected: "@cooler_user something went wrong, try again later",
+		},
+		{
+			name: "ignores chat that isn't a command",
+			text: "great song!",
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			b := newIRCBot(s, "", false, "songvoyage", "", nil)
+
+			ctx := withStreamer(context.Background(), "doomedfingers")
+			assert.Equal(t, tc.expected, b.command(ctx, "cooler_user", tc.mod, tc.text))
+		})
+	}
+}
+
+func TestIRCBotVote(t *testing.T) {
+	s := newServer()
+	b := newIRCBot(s, "", false, "songvoyage", "", nil)
+	ctx := withStreamer(context.Background(), "doomedfingers")
+
+	assert.Equal(t, "@cooler_user there is no poll open", b.command(ctx, "cooler_user", false, "!vote 1"))
+
+	candidates := []*Song{
+		{Artist: "Sum 41", Name: "Fat Lip"},
+		{Artist: "Trivium", Name: "Down From the Sky"},
+	}
+	_, err := s.polls.open("doomedfingers", pollSourceQueue, candidates, time.Minute, func() {})
+	require.NoError(t, err)
+
+	assert.Equal(t, "@cooler_user vote with !vote and the song's number", b.command(ctx, "cooler_user", false, "!vote trivium"))
+	assert.Equal(t, "@cooler_user voted for Trivium - Down From the Sky", b.command(ctx, "cooler_user", false, "!vote #2"))
+	assert.Equal(t, "@cooler_user cooler_user has already voted", b.command(ctx, "cooler_user", false, "!vote 1"))
+	assert.Equal(t, "@other_user vote with a number from 1 to 2", b.command(ctx, "other_user", false, "!vote 3"))
+	assert.Equal(t, []int{0, 1}, s.polls.current("doomedfingers").Tally)
+}
+
+// fakeIRCServer accepts connections from the bot, passing each along to be scripted by the
+// test.
+func fakeIRCServer(t *testing.T) (net.Listener, <-chan net.Conn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	conns := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			conns <- conn
+		}
+	}()
+
+	return ln, conns
+}
+
+func TestIRCBot(t *testing.T) {
+	ln, conns := fakeIRCServer(t)
+
+	// looking up the song being played is held up until released
+	release := make(chan time.Time)
+	db := NewMockdber(t)
+	db.On("find", mock.MatchedBy(func(ctx context.Context) bool {
+		return streamerFrom(ctx) == "doomedfingers"
+	}), tempSetlistName).WaitUntil(release).Return(&Setlist{
+		Name:  tempSetlistName,
+		Songs: []*Song{{Artist: "Sum 41", Name: "Fat Lip"}},
+	}, nil)
+
+	s := newServer()
+	s.db = db
+	b := newIRCBot(s, ln.Addr().String(), false, "songvoyage", "oauth:token", []string{"doomedfingers"})
+	b.minBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.run(ctx)
+
+	expectLines := func(r *bufio.Reader, expected ...string) {
+		t.Helper()
+		for _, e := range expected {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, e, strings.TrimRight(line, "\r\n"))
+		}
+	}
+	accept := func() net.Conn {
+		t.Helper()
+		select {
+		case conn := <-conns:
+			require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+			return conn
+		case <-time.After(5 * time.Second):
+			t.Fatal("bot didn't connect")
+			return nil
+		}
+	}
+
+	conn := accept()
+	r := bufio.NewReader(conn)
+	expectLines(r, "CAP REQ :twitch.tv/tags", "PASS oauth:token", "NICK songvoyage", "JOIN #doomedfingers")
+
+	// PINGs are answered while a command is still being handled
+	fmt.Fprint(conn, ":cooler_user!cooler_user@cooler_user.tmi.twitch.tv PRIVMSG #doomedfingers :!song\r\n")
+	fmt.Fprint(conn, "PING :tmi.twitch.tv\r\n")
+	expectLines(r, "PONG :tmi.twitch.tv")
+
+	close(release)
+	expectLines(r, "PRIVMSG #doomedfingers :Now playing: Sum 41 - Fat Lip")
+
+	// the bot reconnects once the connection is lost
+	conn.Close()
+	conn = accept()
+	expectLines(bufio.NewReader(conn), "CAP REQ :twitch.tv/tags", "PASS oauth:token", "NICK songvoyage", "JOIN #doomedfingers")
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 50*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// waiting stops once ctx is done
+	l = newRateLimiter(1, time.Hour)
+	require.NoError(t, l.wait(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, l.wait(ctx), context.Canceled)
+}
+
+func TestIRCBotSay(t *testing.T) {
+	b := newIRCBot(newServer(), "", false, "songvoyage", "", nil)
+	replies := make(chan string, 1)
+	b.setReplies(replies)
+
+	// a song's name can't end the line early to send commands of its own
+	require.NoError(t, b.say("#doomedfingers", "Now playing: Sum 41 - Fat Lip\r\nQUIT"))
+	assert.Equal(t, "PRIVMSG #doomedfingers :Now playing: Sum 41 - Fat Lip  QUIT", <-replies)
+}
@@ -0,0 +1,47 @@
+package main
+
+// Chart represents a playable chart in a streamer's library of songs. Rhythm games
+// identify charts by a hash of their chart file rather than by artist and name, so those
+// are kept alongside the song's details.
+type Chart struct {
+	Song
+	// MD5 is the hex encoded MD5 checksum of the chart file, used by Clone Hero.
+	MD5 string `json:"md5,omitempty"`
+	// SHA1 is the hex encoded SHA-1 hash of the chart file, used by YARG.
+	SHA1 string `json:"sha1,omitempty"`
+}
+
+// findChart returns the chart in the library with the same artist and name as the provided
+// song or nil if there isn't one.
+func findChart(library []*Chart, s *Song) *Chart {
+	for _, c := range library {
+		if songKey(&c.Song) == songKey(s) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// matchCharts pairs each of the setlist's songs with a chart from the library that has
+// the same artist and name. It returns the matched charts in setlist order along with
+// the songs that had no matching chart.
+func matchCharts(sl *Setlist, library []*Chart) (matched []*Chart, unmatched []*Song) {
+	charts := make(map[string]*Chart, len(library))
+	for _, c := range library {
+		// the first chart for a song wins when a library has duplicates
+		if _, ok := charts[songKey(&c.Song)]; !ok {
+			charts[songKey(&c.Song)] = c
+		}
+	}
+
+	for _, s := range sl.Songs {
+		if c, ok := charts[songKey(s)]; ok {
+			matched = append(matched, c)
+		} else {
+			unmatched = append(unmatched, s)
+		}
+	}
+
+	return matched, unmatched
+}
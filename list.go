@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	defaultListPerPage = 20
+	maxListPerPage     = 100
+)
+
+// listSort is the order setlists are listed in.
+type listSort string
+
+const (
+	listSortName    listSort = "name"
+	listSortCreated listSort = "created"
+	listSortUpdated listSort = "updated"
+	listSortSize    listSort = "size"
+)
+
+// listQuery narrows down and orders the setlists listed for a streamer. Zero values are
+// treated as not set.
+type listQuery struct {
+	// Prefix limits setlists to those whose names start with it, ignoring case.
+	Prefix string
+	// Tags limits setlists to those with all of the tags.
+	Tags       []string
+	Sort       listSort
+	Descending bool
+	// Offset is the number of setlists skipped before the first one listed and Limit the
+	// most listed.
+	Offset int
+	Limit  int
+}
+
+// SetlistListing describes a persisted setlist as it's listed, without its songs.
+type SetlistListing struct {
+	Name      string     `json:"name"`
+	Tags      []string   `json:"tags,omitempty"`
+	Size      int        `json:"size"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// parseListQuery parses the prefix, tag, sort, order, page and per_page query parameters
+// used to list setlists. tag can be repeated to require several tags. Setlists are sorted
+// by name by default, and newest or largest first when sorted by anything else unless
+// order is asc.
+func parseListQuery(args *fasthttp.Args) (listQuery, error) {
+	q := listQuery{
+		Prefix: strings.TrimSpace(string(args.Peek("prefix"))),
+		Sort:   listSort(args.Peek("sort")),
+	}
+	for _, t := range args.PeekMulti("tag") {
+		q.Tags = append(q.Tags, string(t))
+	}
+	q.Tags = normalizeTags(q.Tags)
+
+	switch q.Sort {
+	case "":
+		q.Sort = listSortName
+	case listSortName, listSortCreated, listSortUpdated, listSortSize:
+	default:
+		return q, fmt.Errorf("sort must be one of name, created, updated or size")
+	}
+	switch string(args.Peek("order")) {
+	case "":
+		q.Descending = q.Sort != listSortName
+	case "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return q, fmt.Errorf("order must be asc or desc")
+	}
+
+	page, perPage := 1, defaultListPerPage
+	for _, n := range []struct {
+		arg string
+		dst *int
+	}{
+		{"page", &page},
+		{"per_page", &perPage},
+	} {
+		if !args.Has(n.arg) {
+			continue
+		}
+		v, err := args.GetUint(n.arg)
+		if err != nil || v == 0 {
+			return q, fmt.Errorf("%s must be a positive whole number", n.arg)
+		}
+		*n.dst = v
+	}
+	q.Limit = min(perPage, maxListPerPage)
+	q.Offset = (page - 1) * q.Limit
+
+	return q, nil
+}
+
+// normalizeTags trims and lowercases tags so they match regardless of how they're typed,
+// dropping empty and repeated tags.
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.Join(strings.Fields(t), " "))
+		if t != "" && !slices.Contains(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+
+	return normalized
+}
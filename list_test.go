@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestParseListQuery(t *testing.T) {
+	testCases := []struct {
+		query       string
+		expected    listQuery
+		expectedErr string
+	}{
+		{
+			query:    "",
+			expected: listQuery{Sort: listSortName, Limit: defaultListPerPage},
+		},
+		{
+			query: "prefix=doomed&tag=Metal&tag=charity%20%20stream&tag=metal&sort=updated&page=3&per_page=10",
+			expected: listQuery{
+				Prefix:     "doomed",
+				Tags:       []string{"metal", "charity stream"},
+				Sort:       listSortUpdated,
+				Descending: true,
+				Offset:     20,
+				Limit:      10,
+			},
+		},
+		{
+			query:    "sort=size&order=asc&per_page=500",
+			expected: listQuery{Sort: listSortSize, Limit: maxListPerPage},
+		},
+		{
+			query:       "sort=plays",
+			expectedErr: "sort must be one of name, created, updated or size",
+		},
+		{
+			query:       "page=0",
+			expectedErr: "page must be a positive whole number",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			args := &fasthttp.Args{}
+			args.Parse(tc.query)
+
+			q, err := parseListQuery(args)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
+
+func TestListSetlists(t *testing.T) {
+	created := time.Date(2024, 3, 9, 19, 0, 0, 0, time.UTC)
+
+	db := NewMockdber(t)
+	db.On("list", mock.Anything, listQuery{
+		Tags:       []string{"metal"},
+		Sort:       listSortCreated,
+		Descending: true,
+		Offset:     2,
+		Limit:      2,
+	}).Return([]*SetlistListing{
+		{Name: "Doomed Fingers", Tags: []string{"metal"}, Size: 12, CreatedAt: &created},
+	}, 3, nil)
+
+	s := newServer()
+	s.db = db
+	client := newTestServer(t, routes(s).Handler)
+
+	resp, err := client.Get(lh + "/v1/setlist/list?tag=metal&sort=created&page=2&per_page=2")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"setlists":[`+
+		`{"name":"Doomed Fingers","tags":["metal"],"size":12,"created_at":"2024-03-09T19:00:00Z"}`+
+		`],"total":3,"page":2,"per_page":2}`, string(body))
+}
+
+func TestTagSetlist(t *testing.T) {
+	db := NewMockdber(t)
+	db.On("find", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers"}, nil).Once()
+	db.On("tag", mock.Anything, "Doomed Fingers", []string{"metal", "charity stream"}).Return(nil)
+	db.On("find", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers", Tags: []string{"metal", "charity stream"}}, nil)
+
+	s := newServer()
+	s.db = db
+	client := newTestServer(t, routes(s).Handler)
+
+	resp, err := client.Get(lh + "/v1/setlist/update/tags?name=Doomed%20Fingers&tags=Metal,%20Charity%20Stream,,metal")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"name":"Doomed Fingers","tags":["metal","charity stream"]}`, string(body))
+}
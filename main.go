@@ -2,37 +2,184 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/valyala/fasthttp"
 )
 
+// defaultSongGap is the default number of seconds expected between songs.
+const defaultSongGap = 30
+
 type server struct {
 	db dber
+	// songGap is the number of seconds expected between songs when estimating how long a
+	// setlist will take to play.
+	songGap int
+	// notifiers are told about a setlist's new state whenever a request changes it.
+	notifiers []notifier
+	// announcers relay messages to a streamer's chat, e.g. rejections of requests.
+	announcers []announcer
+	// modToken is the token bots send with requests made by the streamer or their mods.
+	// No request is treated as a mod's when it's empty.
+	modToken []byte
+	// tipSecret is the secret tip events must be signed with. The tips webhook is
+	// disabled when it's empty.
+	tipSecret []byte
+	// tipSeen dedupes tip events, so each is only handled once.
+	tipSeen *seenMessages
+	// tipTiers maps tip events to the priority tier their songs are requested in.
+	tipTiers tipTiers
+	// twitchSecret is the secret Twitch EventSub messages are signed with. The EventSub
+	// webhook is disabled when it's empty.
+	twitchSecret []byte
+	// twitchRewardID is the ID of the channel point reward viewers redeem to request
+	// songs. Rewards are matched by ID since their titles can be changed or reused.
+	twitchRewardID string
+	// eventSubSeen dedupes retried EventSub deliveries.
+	eventSubSeen *seenMessages
+	// discordKey is the public key of the Discord application interactions are signed
+	// with. The interactions endpoint is disabled when it's nil.
+	discordKey ed25519.PublicKey
+	// discordGuilds maps Discord server IDs to the streamer each is linked to.
+	discordGuilds map[string]string
+	// polls holds each streamer's viewer poll on the next song.
+	polls *polls
+	// trashRetention is how long deleted setlists are kept in the trash before they're
+	// purged.
+	trashRetention time.Duration
+}
+
+// notifier provides the method notify, used to pass along a setlist's state after it has
+// been changed.
+type notifier interface {
+	notify(ctx context.Context, sl *Setlist) error
+}
+
+// announcer provides the method announce, used to relay a message to the chat of the
+// streamer ctx is scoped to.
+type announcer interface {
+	announce(ctx context.Context, msg string) error
 }
 
 func newServer() *server {
 	return &server{
-		db: newDB(),
+		db:             newDB(),
+		songGap:        defaultSongGap,
+		tipSeen:        newSeenMessages(),
+		eventSubSeen:   newSeenMessages(),
+		polls:          newPolls(),
+		trashRetention: defaultTrashRetention,
 	}
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "library" {
+		if err := libraryCommand(os.Args[2:]); err != nil {
+			log.Fatalf("library: %s", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "discord" {
+		if err := discordCommand(os.Args[2:]); err != nil {
+			log.Fatalf("discord: %s", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tip" {
+		if err := tipCommand(os.Args[2:]); err != nil {
+			log.Fatalf("tip: %s", err)
+		}
+		return
+	}
+
 	addr := flag.String("addr", ":8080", "TCP address to listen to")
+	obsDir := flag.String("obs-dir", "", "directory to write OBS text source files to, disabled when empty")
+	obsStreamer := flag.String("obs-streamer", "", "streamer whose temporary setlist is written to the OBS files")
+	obsNowPlaying := flag.String("obs-nowplaying-format", defaultOBSSongFormat, "template used for each line of nowplaying.txt")
+	obsNext := flag.String("obs-next-format", defaultOBSSongFormat, "template used for each line of next.txt")
+	obsQueue := flag.String("obs-queue-format", defaultOBSQueueFormat, "template used for each line of queue.txt")
+	modToken := flag.String("mod-token", os.Getenv("SONGVOYAGE_MOD_TOKEN"), "token bots send as mod_token with requests made by the streamer or their mods, disabled when empty")
+	tipSecret := flag.String("tip-secret", os.Getenv("SONGVOYAGE_TIP_SECRET"), "secret tip webhook events are signed with, disabled when empty")
+	tipTierSpec := flag.String("tip-tiers", "", "priority tiers for tip webhook events, e.g. \"tip:500=1,2000=2;bits:100=1\"")
+	twitchSecret := flag.String("twitch-secret", os.Getenv("SONGVOYAGE_TWITCH_SECRET"), "secret Twitch EventSub subscriptions are created with, disabled when empty")
+	twitchRewardID := flag.String("twitch-reward-id", "", "ID of the channel point reward used for song requests, required with -twitch-secret")
+	ircAddr := flag.String("irc-addr", "", "IRC server for the built-in chat bot to connect to, e.g. irc.chat.twitch.tv:6697, disabled when empty")
+	ircTLS := flag.Bool("irc-tls", true, "connect to the IRC server over TLS")
+	ircNick := flag.String("irc-nick", "", "nickname of the chat bot")
+	ircPass := flag.String("irc-pass", os.Getenv("SONGVOYAGE_IRC_PASS"), "password the chat bot connects with, e.g. oauth:token for Twitch")
+	ircChannels := flag.String("irc-channels", "", "comma separated channels for the chat bot to join, each named after its streamer")
+	discordKey := flag.String("discord-public-key", "", "hex encoded public key of the Discord application, disabled when empty")
+	discordGuilds := flag.String("discord-guilds", "", "comma separated guildID=streamer pairs linking Discord servers to streamers")
+	songGap := flag.Duration("song-gap", defaultSongGap*time.Second, "time expected between songs when estimating setlist lengths")
+	versions := flag.Int("versions", defaultVersions, "number of versions of each setlist kept for undo, disabled when 0")
+	trashRetention := flag.Duration("trash-retention", defaultTrashRetention, "time deleted setlists are kept in the trash before they're purged")
 	flag.Parse()
 
 	s := newServer()
+	s.db = &versioned{dber: s.db, keep: *versions}
+	s.songGap = int(songGap.Seconds())
+	s.trashRetention = *trashRetention
+	s.modToken = []byte(*modToken)
+	s.tipSecret = []byte(*tipSecret)
+	s.twitchSecret = []byte(*twitchSecret)
+	s.twitchRewardID = *twitchRewardID
+	if len(s.twitchSecret) > 0 && s.twitchRewardID == "" {
+		log.Fatalf("-twitch-reward-id is required with -twitch-secret")
+	}
+
+	tiers, err := parseTipTiers(*tipTierSpec)
+	if err != nil {
+		log.Fatalf("parsing tip tiers: %s", err)
+	}
+	s.tipTiers = tiers
+
+	if *discordKey != "" {
+		key, err := hex.DecodeString(*discordKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			log.Fatalf("discord public key must be a hex encoded ed25519 public key")
+		}
+		s.discordKey = key
+	}
+	if s.discordGuilds, err = parseDiscordGuilds(*discordGuilds); err != nil {
+		log.Fatalf("parsing discord guilds: %s", err)
+	}
+
+	if *obsDir != "" {
+		sink, err := newOBSSink(*obsDir, *obsStreamer, *obsNowPlaying, *obsNext, *obsQueue)
+		if err != nil {
+			log.Fatalf("configuring obs files: %s", err)
+		}
+		s.notifiers = append(s.notifiers, sink)
+	}
 	r := routes(s)
 
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go purgeTrash(purgeCtx, s.db, trashPurgeInterval)
+
+	botCtx, stopBot := context.WithCancel(context.Background())
+	defer stopBot()
+	if *ircAddr != "" {
+		channels := strings.FieldsFunc(*ircChannels, func(r rune) bool { return r == ',' })
+		bot := newIRCBot(s, *ircAddr, *ircTLS, *ircNick, *ircPass, channels)
+		go bot.run(botCtx)
+		s.announcers = append(s.announcers, bot)
+	}
+
 	fs := &fasthttp.Server{
-		Handler: r.Handler,
+		Handler: scopeStreamer(groupChanges(r.Handler)),
+		// large enough for uploading libraries with tens of thousands of charts
+		MaxRequestBodySize: 32 << 20,
 	}
 
 	go func() {
@@ -50,6 +197,7 @@ func main() {
 	defer cancel()
 
 	log.Println("shutting down server")
+	stopBot()
 	if err := fs.ShutdownWithContext(ctx); err != nil {
 		log.Fatalf("shutting down server: %s", err)
 	}
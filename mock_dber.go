@@ -6,6 +6,8 @@ import (
 	context "context"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Mockdber is an autogenerated mock type for the dber type
@@ -21,17 +23,17 @@ func (_m *Mockdber) EXPECT() *Mockdber_Expecter {
 	return &Mockdber_Expecter{mock: &_m.Mock}
 }
 
-// add provides a mock function with given fields: ctx, setlistName, artistName, songName
-func (_m *Mockdber) add(ctx context.Context, setlistName string, artistName string, songName string) error {
-	ret := _m.Called(ctx, setlistName, artistName, songName)
+// add provides a mock function with given fields: ctx, setlistName, song
+func (_m *Mockdber) add(ctx context.Context, setlistName string, song *Song) error {
+	ret := _m.Called(ctx, setlistName, song)
 
 	if len(ret) == 0 {
 		panic("no return value specified for add")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
-		r0 = rf(ctx, setlistName, artistName, songName)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *Song) error); ok {
+		r0 = rf(ctx, setlistName, song)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -47,15 +49,14 @@ type Mockdber_add_Call struct {
 // add is a helper method to define mock.On call
 //   - ctx context.Context
 //   - setlistName string
-//   - artistName string
-//   - songName string
-func (_e *Mockdber_Expecter) add(ctx interface{}, setlistName interface{}, artistName interface{}, songName interface{}) *Mockdber_add_Call {
-	return &Mockdber_add_Call{Call: _e.mock.On("add", ctx, setlistName, artistName, songName)}
+//   - song *Song
+func (_e *Mockdber_Expecter) add(ctx interface{}, setlistName interface{}, song interface{}) *Mockdber_add_Call {
+	return &Mockdber_add_Call{Call: _e.mock.On("add", ctx, setlistName, song)}
 }
 
-func (_c *Mockdber_add_Call) Run(run func(ctx context.Context, setlistName string, artistName string, songName string)) *Mockdber_add_Call {
+func (_c *Mockdber_add_Call) Run(run func(ctx context.Context, setlistName string, song *Song)) *Mockdber_add_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(*Song))
 	})
 	return _c
 }
@@ -65,7 +66,112 @@ func (_c *Mockdber_add_Call) Return(_a0 error) *Mockdber_add_Call {
 	return _c
 }
 
-func (_c *Mockdber_add_Call) RunAndReturn(run func(context.Context, string, string, string) error) *Mockdber_add_Call {
+func (_c *Mockdber_add_Call) RunAndReturn(run func(context.Context, string, *Song) error) *Mockdber_add_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// block provides a mock function with given fields: ctx, b
+func (_m *Mockdber) block(ctx context.Context, b *Blocklist) error {
+	ret := _m.Called(ctx, b)
+
+	if len(ret) == 0 {
+		panic("no return value specified for block")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Blocklist) error); ok {
+		r0 = rf(ctx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_block_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'block'
+type Mockdber_block_Call struct {
+	*mock.Call
+}
+
+// block is a helper method to define mock.On call
+//   - ctx context.Context
+//   - b *Blocklist
+func (_e *Mockdber_Expecter) block(ctx interface{}, b interface{}) *Mockdber_block_Call {
+	return &Mockdber_block_Call{Call: _e.mock.On("block", ctx, b)}
+}
+
+func (_c *Mockdber_block_Call) Run(run func(ctx context.Context, b *Blocklist)) *Mockdber_block_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Blocklist))
+	})
+	return _c
+}
+
+func (_c *Mockdber_block_Call) Return(_a0 error) *Mockdber_block_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_block_Call) RunAndReturn(run func(context.Context, *Blocklist) error) *Mockdber_block_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// blocklist provides a mock function with given fields: ctx
+func (_m *Mockdber) blocklist(ctx context.Context) (*Blocklist, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for blocklist")
+	}
+
+	var r0 *Blocklist
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*Blocklist, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *Blocklist); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Blocklist)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_blocklist_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'blocklist'
+type Mockdber_blocklist_Call struct {
+	*mock.Call
+}
+
+// blocklist is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Mockdber_Expecter) blocklist(ctx interface{}) *Mockdber_blocklist_Call {
+	return &Mockdber_blocklist_Call{Call: _e.mock.On("blocklist", ctx)}
+}
+
+func (_c *Mockdber_blocklist_Call) Run(run func(ctx context.Context)) *Mockdber_blocklist_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Mockdber_blocklist_Call) Return(_a0 *Blocklist, _a1 error) *Mockdber_blocklist_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_blocklist_Call) RunAndReturn(run func(context.Context) (*Blocklist, error)) *Mockdber_blocklist_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -176,17 +282,17 @@ func (_c *Mockdber_create_Call) RunAndReturn(run func(context.Context, string) (
 	return _c
 }
 
-// delete provides a mock function with given fields: ctx, name
-func (_m *Mockdber) delete(ctx context.Context, name string) error {
-	ret := _m.Called(ctx, name)
+// delete provides a mock function with given fields: ctx, name, expires
+func (_m *Mockdber) delete(ctx context.Context, name string, expires time.Time) error {
+	ret := _m.Called(ctx, name, expires)
 
 	if len(ret) == 0 {
 		panic("no return value specified for delete")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, name)
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, name, expires)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -202,13 +308,14 @@ type Mockdber_delete_Call struct {
 // delete is a helper method to define mock.On call
 //   - ctx context.Context
 //   - name string
-func (_e *Mockdber_Expecter) delete(ctx interface{}, name interface{}) *Mockdber_delete_Call {
-	return &Mockdber_delete_Call{Call: _e.mock.On("delete", ctx, name)}
+//   - expires time.Time
+func (_e *Mockdber_Expecter) delete(ctx interface{}, name interface{}, expires interface{}) *Mockdber_delete_Call {
+	return &Mockdber_delete_Call{Call: _e.mock.On("delete", ctx, name, expires)}
 }
 
-func (_c *Mockdber_delete_Call) Run(run func(ctx context.Context, name string)) *Mockdber_delete_Call {
+func (_c *Mockdber_delete_Call) Run(run func(ctx context.Context, name string, expires time.Time)) *Mockdber_delete_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
 	})
 	return _c
 }
@@ -218,7 +325,7 @@ func (_c *Mockdber_delete_Call) Return(_a0 error) *Mockdber_delete_Call {
 	return _c
 }
 
-func (_c *Mockdber_delete_Call) RunAndReturn(run func(context.Context, string) error) *Mockdber_delete_Call {
+func (_c *Mockdber_delete_Call) RunAndReturn(run func(context.Context, string, time.Time) error) *Mockdber_delete_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -282,17 +389,17 @@ func (_c *Mockdber_find_Call) RunAndReturn(run func(context.Context, string) (*S
 	return _c
 }
 
-// remove provides a mock function with given fields: ctx, setlistName, songName, songNumber
-func (_m *Mockdber) remove(ctx context.Context, setlistName string, songName string, songNumber int) error {
-	ret := _m.Called(ctx, setlistName, songName, songNumber)
+// forget provides a mock function with given fields: ctx, name, id
+func (_m *Mockdber) forget(ctx context.Context, name string, id string) error {
+	ret := _m.Called(ctx, name, id)
 
 	if len(ret) == 0 {
-		panic("no return value specified for remove")
+		panic("no return value specified for forget")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) error); ok {
-		r0 = rf(ctx, setlistName, songName, songNumber)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, name, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -300,60 +407,59 @@ func (_m *Mockdber) remove(ctx context.Context, setlistName string, songName str
 	return r0
 }
 
-// Mockdber_remove_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'remove'
-type Mockdber_remove_Call struct {
+// Mockdber_forget_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'forget'
+type Mockdber_forget_Call struct {
 	*mock.Call
 }
 
-// remove is a helper method to define mock.On call
+// forget is a helper method to define mock.On call
 //   - ctx context.Context
-//   - setlistName string
-//   - songName string
-//   - songNumber int
-func (_e *Mockdber_Expecter) remove(ctx interface{}, setlistName interface{}, songName interface{}, songNumber interface{}) *Mockdber_remove_Call {
-	return &Mockdber_remove_Call{Call: _e.mock.On("remove", ctx, setlistName, songName, songNumber)}
+//   - name string
+//   - id string
+func (_e *Mockdber_Expecter) forget(ctx interface{}, name interface{}, id interface{}) *Mockdber_forget_Call {
+	return &Mockdber_forget_Call{Call: _e.mock.On("forget", ctx, name, id)}
 }
 
-func (_c *Mockdber_remove_Call) Run(run func(ctx context.Context, setlistName string, songName string, songNumber int)) *Mockdber_remove_Call {
+func (_c *Mockdber_forget_Call) Run(run func(ctx context.Context, name string, id string)) *Mockdber_forget_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(int))
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
 	})
 	return _c
 }
 
-func (_c *Mockdber_remove_Call) Return(_a0 error) *Mockdber_remove_Call {
+func (_c *Mockdber_forget_Call) Return(_a0 error) *Mockdber_forget_Call {
 	_c.Call.Return(_a0)
 	return _c
 }
 
-func (_c *Mockdber_remove_Call) RunAndReturn(run func(context.Context, string, string, int) error) *Mockdber_remove_Call {
+func (_c *Mockdber_forget_Call) RunAndReturn(run func(context.Context, string, string) error) *Mockdber_forget_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// save provides a mock function with given fields: ctx, name
-func (_m *Mockdber) save(ctx context.Context, name string) (*Setlist, error) {
-	ret := _m.Called(ctx, name)
+// history provides a mock function with given fields: ctx, q
+func (_m *Mockdber) history(ctx context.Context, q historyQuery) ([]*Play, error) {
+	ret := _m.Called(ctx, q)
 
 	if len(ret) == 0 {
-		panic("no return value specified for save")
+		panic("no return value specified for history")
 	}
 
-	var r0 *Setlist
+	var r0 []*Play
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*Setlist, error)); ok {
-		return rf(ctx, name)
+	if rf, ok := ret.Get(0).(func(context.Context, historyQuery) ([]*Play, error)); ok {
+		return rf(ctx, q)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *Setlist); ok {
-		r0 = rf(ctx, name)
+	if rf, ok := ret.Get(0).(func(context.Context, historyQuery) []*Play); ok {
+		r0 = rf(ctx, q)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*Setlist)
+			r0 = ret.Get(0).([]*Play)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, name)
+	if rf, ok := ret.Get(1).(func(context.Context, historyQuery) error); ok {
+		r1 = rf(ctx, q)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -361,58 +467,58 @@ func (_m *Mockdber) save(ctx context.Context, name string) (*Setlist, error) {
 	return r0, r1
 }
 
-// Mockdber_save_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'save'
-type Mockdber_save_Call struct {
+// Mockdber_history_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'history'
+type Mockdber_history_Call struct {
 	*mock.Call
 }
 
-// save is a helper method to define mock.On call
+// history is a helper method to define mock.On call
 //   - ctx context.Context
-//   - name string
-func (_e *Mockdber_Expecter) save(ctx interface{}, name interface{}) *Mockdber_save_Call {
-	return &Mockdber_save_Call{Call: _e.mock.On("save", ctx, name)}
+//   - q historyQuery
+func (_e *Mockdber_Expecter) history(ctx interface{}, q interface{}) *Mockdber_history_Call {
+	return &Mockdber_history_Call{Call: _e.mock.On("history", ctx, q)}
 }
 
-func (_c *Mockdber_save_Call) Run(run func(ctx context.Context, name string)) *Mockdber_save_Call {
+func (_c *Mockdber_history_Call) Run(run func(ctx context.Context, q historyQuery)) *Mockdber_history_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(historyQuery))
 	})
 	return _c
 }
 
-func (_c *Mockdber_save_Call) Return(_a0 *Setlist, _a1 error) *Mockdber_save_Call {
+func (_c *Mockdber_history_Call) Return(_a0 []*Play, _a1 error) *Mockdber_history_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *Mockdber_save_Call) RunAndReturn(run func(context.Context, string) (*Setlist, error)) *Mockdber_save_Call {
+func (_c *Mockdber_history_Call) RunAndReturn(run func(context.Context, historyQuery) ([]*Play, error)) *Mockdber_history_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// update provides a mock function with given fields: ctx, oldName, newName
-func (_m *Mockdber) update(ctx context.Context, oldName string, newName string) (*Setlist, error) {
-	ret := _m.Called(ctx, oldName, newName)
+// library provides a mock function with given fields: ctx
+func (_m *Mockdber) library(ctx context.Context) ([]*Chart, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for update")
+		panic("no return value specified for library")
 	}
 
-	var r0 *Setlist
+	var r0 []*Chart
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*Setlist, error)); ok {
-		return rf(ctx, oldName, newName)
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*Chart, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Setlist); ok {
-		r0 = rf(ctx, oldName, newName)
+	if rf, ok := ret.Get(0).(func(context.Context) []*Chart); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*Setlist)
+			r0 = ret.Get(0).([]*Chart)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, oldName, newName)
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -420,32 +526,1420 @@ func (_m *Mockdber) update(ctx context.Context, oldName string, newName string)
 	return r0, r1
 }
 
-// Mockdber_update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'update'
-type Mockdber_update_Call struct {
+// Mockdber_library_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'library'
+type Mockdber_library_Call struct {
 	*mock.Call
 }
 
-// update is a helper method to define mock.On call
+// library is a helper method to define mock.On call
 //   - ctx context.Context
-//   - oldName string
-//   - newName string
-func (_e *Mockdber_Expecter) update(ctx interface{}, oldName interface{}, newName interface{}) *Mockdber_update_Call {
-	return &Mockdber_update_Call{Call: _e.mock.On("update", ctx, oldName, newName)}
+func (_e *Mockdber_Expecter) library(ctx interface{}) *Mockdber_library_Call {
+	return &Mockdber_library_Call{Call: _e.mock.On("library", ctx)}
 }
 
-func (_c *Mockdber_update_Call) Run(run func(ctx context.Context, oldName string, newName string)) *Mockdber_update_Call {
+func (_c *Mockdber_library_Call) Run(run func(ctx context.Context)) *Mockdber_library_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string), args[2].(string))
+		run(args[0].(context.Context))
 	})
 	return _c
 }
 
-func (_c *Mockdber_update_Call) Return(_a0 *Setlist, _a1 error) *Mockdber_update_Call {
+func (_c *Mockdber_library_Call) Return(_a0 []*Chart, _a1 error) *Mockdber_library_Call {
 	_c.Call.Return(_a0, _a1)
 	return _c
 }
 
-func (_c *Mockdber_update_Call) RunAndReturn(run func(context.Context, string, string) (*Setlist, error)) *Mockdber_update_Call {
+func (_c *Mockdber_library_Call) RunAndReturn(run func(context.Context) ([]*Chart, error)) *Mockdber_library_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// list provides a mock function with given fields: ctx, q
+func (_m *Mockdber) list(ctx context.Context, q listQuery) ([]*SetlistListing, int, error) {
+	ret := _m.Called(ctx, q)
+
+	if len(ret) == 0 {
+		panic("no return value specified for list")
+	}
+
+	var r0 []*SetlistListing
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, listQuery) ([]*SetlistListing, int, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, listQuery) []*SetlistListing); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*SetlistListing)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, listQuery) int); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, listQuery) error); ok {
+		r2 = rf(ctx, q)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Mockdber_list_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'list'
+type Mockdber_list_Call struct {
+	*mock.Call
+}
+
+// list is a helper method to define mock.On call
+//   - ctx context.Context
+//   - q listQuery
+func (_e *Mockdber_Expecter) list(ctx interface{}, q interface{}) *Mockdber_list_Call {
+	return &Mockdber_list_Call{Call: _e.mock.On("list", ctx, q)}
+}
+
+func (_c *Mockdber_list_Call) Run(run func(ctx context.Context, q listQuery)) *Mockdber_list_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(listQuery))
+	})
+	return _c
+}
+
+func (_c *Mockdber_list_Call) Return(_a0 []*SetlistListing, _a1 int, _a2 error) *Mockdber_list_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *Mockdber_list_Call) RunAndReturn(run func(context.Context, listQuery) ([]*SetlistListing, int, error)) *Mockdber_list_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// move provides a mock function with given fields: ctx, setlistName, from, to
+func (_m *Mockdber) move(ctx context.Context, setlistName string, from int, to int) error {
+	ret := _m.Called(ctx, setlistName, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for move")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, int) error); ok {
+		r0 = rf(ctx, setlistName, from, to)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_move_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'move'
+type Mockdber_move_Call struct {
+	*mock.Call
+}
+
+// move is a helper method to define mock.On call
+//   - ctx context.Context
+//   - setlistName string
+//   - from int
+//   - to int
+func (_e *Mockdber_Expecter) move(ctx interface{}, setlistName interface{}, from interface{}, to interface{}) *Mockdber_move_Call {
+	return &Mockdber_move_Call{Call: _e.mock.On("move", ctx, setlistName, from, to)}
+}
+
+func (_c *Mockdber_move_Call) Run(run func(ctx context.Context, setlistName string, from int, to int)) *Mockdber_move_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *Mockdber_move_Call) Return(_a0 error) *Mockdber_move_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_move_Call) RunAndReturn(run func(context.Context, string, int, int) error) *Mockdber_move_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// pending provides a mock function with given fields: ctx
+func (_m *Mockdber) pending(ctx context.Context) ([]*PendingRequest, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for pending")
+	}
+
+	var r0 []*PendingRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*PendingRequest, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*PendingRequest); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*PendingRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_pending_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'pending'
+type Mockdber_pending_Call struct {
+	*mock.Call
+}
+
+// pending is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Mockdber_Expecter) pending(ctx interface{}) *Mockdber_pending_Call {
+	return &Mockdber_pending_Call{Call: _e.mock.On("pending", ctx)}
+}
+
+func (_c *Mockdber_pending_Call) Run(run func(ctx context.Context)) *Mockdber_pending_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Mockdber_pending_Call) Return(_a0 []*PendingRequest, _a1 error) *Mockdber_pending_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_pending_Call) RunAndReturn(run func(context.Context) ([]*PendingRequest, error)) *Mockdber_pending_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// play provides a mock function with given fields: ctx, p
+func (_m *Mockdber) play(ctx context.Context, p *Play) error {
+	ret := _m.Called(ctx, p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for play")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Play) error); ok {
+		r0 = rf(ctx, p)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_play_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'play'
+type Mockdber_play_Call struct {
+	*mock.Call
+}
+
+// play is a helper method to define mock.On call
+//   - ctx context.Context
+//   - p *Play
+func (_e *Mockdber_Expecter) play(ctx interface{}, p interface{}) *Mockdber_play_Call {
+	return &Mockdber_play_Call{Call: _e.mock.On("play", ctx, p)}
+}
+
+func (_c *Mockdber_play_Call) Run(run func(ctx context.Context, p *Play)) *Mockdber_play_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Play))
+	})
+	return _c
+}
+
+func (_c *Mockdber_play_Call) Return(_a0 error) *Mockdber_play_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_play_Call) RunAndReturn(run func(context.Context, *Play) error) *Mockdber_play_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// purge provides a mock function with given fields: ctx, before
+func (_m *Mockdber) purge(ctx context.Context, before time.Time) error {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for purge")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) error); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_purge_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'purge'
+type Mockdber_purge_Call struct {
+	*mock.Call
+}
+
+// purge is a helper method to define mock.On call
+//   - ctx context.Context
+//   - before time.Time
+func (_e *Mockdber_Expecter) purge(ctx interface{}, before interface{}) *Mockdber_purge_Call {
+	return &Mockdber_purge_Call{Call: _e.mock.On("purge", ctx, before)}
+}
+
+func (_c *Mockdber_purge_Call) Run(run func(ctx context.Context, before time.Time)) *Mockdber_purge_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Time))
+	})
+	return _c
+}
+
+func (_c *Mockdber_purge_Call) Return(_a0 error) *Mockdber_purge_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_purge_Call) RunAndReturn(run func(context.Context, time.Time) error) *Mockdber_purge_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// record provides a mock function with given fields: ctx, sc
+func (_m *Mockdber) record(ctx context.Context, sc *Score) error {
+	ret := _m.Called(ctx, sc)
+
+	if len(ret) == 0 {
+		panic("no return value specified for record")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Score) error); ok {
+		r0 = rf(ctx, sc)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_record_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'record'
+type Mockdber_record_Call struct {
+	*mock.Call
+}
+
+// record is a helper method to define mock.On call
+//   - ctx context.Context
+//   - sc *Score
+func (_e *Mockdber_Expecter) record(ctx interface{}, sc interface{}) *Mockdber_record_Call {
+	return &Mockdber_record_Call{Call: _e.mock.On("record", ctx, sc)}
+}
+
+func (_c *Mockdber_record_Call) Run(run func(ctx context.Context, sc *Score)) *Mockdber_record_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Score))
+	})
+	return _c
+}
+
+func (_c *Mockdber_record_Call) Return(_a0 error) *Mockdber_record_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_record_Call) RunAndReturn(run func(context.Context, *Score) error) *Mockdber_record_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// remove provides a mock function with given fields: ctx, setlistName, songName, songNumber
+func (_m *Mockdber) remove(ctx context.Context, setlistName string, songName string, songNumber int) error {
+	ret := _m.Called(ctx, setlistName, songName, songNumber)
+
+	if len(ret) == 0 {
+		panic("no return value specified for remove")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) error); ok {
+		r0 = rf(ctx, setlistName, songName, songNumber)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_remove_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'remove'
+type Mockdber_remove_Call struct {
+	*mock.Call
+}
+
+// remove is a helper method to define mock.On call
+//   - ctx context.Context
+//   - setlistName string
+//   - songName string
+//   - songNumber int
+func (_e *Mockdber_Expecter) remove(ctx interface{}, setlistName interface{}, songName interface{}, songNumber interface{}) *Mockdber_remove_Call {
+	return &Mockdber_remove_Call{Call: _e.mock.On("remove", ctx, setlistName, songName, songNumber)}
+}
+
+func (_c *Mockdber_remove_Call) Run(run func(ctx context.Context, setlistName string, songName string, songNumber int)) *Mockdber_remove_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *Mockdber_remove_Call) Return(_a0 error) *Mockdber_remove_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_remove_Call) RunAndReturn(run func(context.Context, string, string, int) error) *Mockdber_remove_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// resolve provides a mock function with given fields: ctx, id
+func (_m *Mockdber) resolve(ctx context.Context, id string) (*PendingRequest, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for resolve")
+	}
+
+	var r0 *PendingRequest
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*PendingRequest, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *PendingRequest); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*PendingRequest)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_resolve_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'resolve'
+type Mockdber_resolve_Call struct {
+	*mock.Call
+}
+
+// resolve is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Mockdber_Expecter) resolve(ctx interface{}, id interface{}) *Mockdber_resolve_Call {
+	return &Mockdber_resolve_Call{Call: _e.mock.On("resolve", ctx, id)}
+}
+
+func (_c *Mockdber_resolve_Call) Run(run func(ctx context.Context, id string)) *Mockdber_resolve_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_resolve_Call) Return(_a0 *PendingRequest, _a1 error) *Mockdber_resolve_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_resolve_Call) RunAndReturn(run func(context.Context, string) (*PendingRequest, error)) *Mockdber_resolve_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// review provides a mock function with given fields: ctx, on
+func (_m *Mockdber) review(ctx context.Context, on bool) error {
+	ret := _m.Called(ctx, on)
+
+	if len(ret) == 0 {
+		panic("no return value specified for review")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool) error); ok {
+		r0 = rf(ctx, on)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_review_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'review'
+type Mockdber_review_Call struct {
+	*mock.Call
+}
+
+// review is a helper method to define mock.On call
+//   - ctx context.Context
+//   - on bool
+func (_e *Mockdber_Expecter) review(ctx interface{}, on interface{}) *Mockdber_review_Call {
+	return &Mockdber_review_Call{Call: _e.mock.On("review", ctx, on)}
+}
+
+func (_c *Mockdber_review_Call) Run(run func(ctx context.Context, on bool)) *Mockdber_review_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *Mockdber_review_Call) Return(_a0 error) *Mockdber_review_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_review_Call) RunAndReturn(run func(context.Context, bool) error) *Mockdber_review_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// reviewing provides a mock function with given fields: ctx
+func (_m *Mockdber) reviewing(ctx context.Context) (bool, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for reviewing")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (bool, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) bool); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_reviewing_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'reviewing'
+type Mockdber_reviewing_Call struct {
+	*mock.Call
+}
+
+// reviewing is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Mockdber_Expecter) reviewing(ctx interface{}) *Mockdber_reviewing_Call {
+	return &Mockdber_reviewing_Call{Call: _e.mock.On("reviewing", ctx)}
+}
+
+func (_c *Mockdber_reviewing_Call) Run(run func(ctx context.Context)) *Mockdber_reviewing_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Mockdber_reviewing_Call) Return(_a0 bool, _a1 error) *Mockdber_reviewing_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_reviewing_Call) RunAndReturn(run func(context.Context) (bool, error)) *Mockdber_reviewing_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// save provides a mock function with given fields: ctx, name
+func (_m *Mockdber) save(ctx context.Context, name string) (*Setlist, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for save")
+	}
+
+	var r0 *Setlist
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*Setlist, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *Setlist); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Setlist)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_save_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'save'
+type Mockdber_save_Call struct {
+	*mock.Call
+}
+
+// save is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *Mockdber_Expecter) save(ctx interface{}, name interface{}) *Mockdber_save_Call {
+	return &Mockdber_save_Call{Call: _e.mock.On("save", ctx, name)}
+}
+
+func (_c *Mockdber_save_Call) Run(run func(ctx context.Context, name string)) *Mockdber_save_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_save_Call) Return(_a0 *Setlist, _a1 error) *Mockdber_save_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_save_Call) RunAndReturn(run func(context.Context, string) (*Setlist, error)) *Mockdber_save_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// scores provides a mock function with given fields: ctx, s
+func (_m *Mockdber) scores(ctx context.Context, s *Song) ([]*Score, error) {
+	ret := _m.Called(ctx, s)
+
+	if len(ret) == 0 {
+		panic("no return value specified for scores")
+	}
+
+	var r0 []*Score
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Song) ([]*Score, error)); ok {
+		return rf(ctx, s)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *Song) []*Score); ok {
+		r0 = rf(ctx, s)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Score)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *Song) error); ok {
+		r1 = rf(ctx, s)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_scores_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'scores'
+type Mockdber_scores_Call struct {
+	*mock.Call
+}
+
+// scores is a helper method to define mock.On call
+//   - ctx context.Context
+//   - s *Song
+func (_e *Mockdber_Expecter) scores(ctx interface{}, s interface{}) *Mockdber_scores_Call {
+	return &Mockdber_scores_Call{Call: _e.mock.On("scores", ctx, s)}
+}
+
+func (_c *Mockdber_scores_Call) Run(run func(ctx context.Context, s *Song)) *Mockdber_scores_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Song))
+	})
+	return _c
+}
+
+func (_c *Mockdber_scores_Call) Return(_a0 []*Score, _a1 error) *Mockdber_scores_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_scores_Call) RunAndReturn(run func(context.Context, *Song) ([]*Score, error)) *Mockdber_scores_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// session provides a mock function with given fields: ctx, id
+func (_m *Mockdber) session(ctx context.Context, id string) (*Session, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for session")
+	}
+
+	var r0 *Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*Session, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *Session); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_session_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'session'
+type Mockdber_session_Call struct {
+	*mock.Call
+}
+
+// session is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *Mockdber_Expecter) session(ctx interface{}, id interface{}) *Mockdber_session_Call {
+	return &Mockdber_session_Call{Call: _e.mock.On("session", ctx, id)}
+}
+
+func (_c *Mockdber_session_Call) Run(run func(ctx context.Context, id string)) *Mockdber_session_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_session_Call) Return(_a0 *Session, _a1 error) *Mockdber_session_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_session_Call) RunAndReturn(run func(context.Context, string) (*Session, error)) *Mockdber_session_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// sessions provides a mock function with given fields: ctx
+func (_m *Mockdber) sessions(ctx context.Context) ([]*Session, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for sessions")
+	}
+
+	var r0 []*Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*Session, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*Session); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_sessions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'sessions'
+type Mockdber_sessions_Call struct {
+	*mock.Call
+}
+
+// sessions is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Mockdber_Expecter) sessions(ctx interface{}) *Mockdber_sessions_Call {
+	return &Mockdber_sessions_Call{Call: _e.mock.On("sessions", ctx)}
+}
+
+func (_c *Mockdber_sessions_Call) Run(run func(ctx context.Context)) *Mockdber_sessions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Mockdber_sessions_Call) Return(_a0 []*Session, _a1 error) *Mockdber_sessions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_sessions_Call) RunAndReturn(run func(context.Context) ([]*Session, error)) *Mockdber_sessions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// snapshot provides a mock function with given fields: ctx, v, keep
+func (_m *Mockdber) snapshot(ctx context.Context, v *Version, keep int) error {
+	ret := _m.Called(ctx, v, keep)
+
+	if len(ret) == 0 {
+		panic("no return value specified for snapshot")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Version, int) error); ok {
+		r0 = rf(ctx, v, keep)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_snapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'snapshot'
+type Mockdber_snapshot_Call struct {
+	*mock.Call
+}
+
+// snapshot is a helper method to define mock.On call
+//   - ctx context.Context
+//   - v *Version
+//   - keep int
+func (_e *Mockdber_Expecter) snapshot(ctx interface{}, v interface{}, keep interface{}) *Mockdber_snapshot_Call {
+	return &Mockdber_snapshot_Call{Call: _e.mock.On("snapshot", ctx, v, keep)}
+}
+
+func (_c *Mockdber_snapshot_Call) Run(run func(ctx context.Context, v *Version, keep int)) *Mockdber_snapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Version), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Mockdber_snapshot_Call) Return(_a0 error) *Mockdber_snapshot_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_snapshot_Call) RunAndReturn(run func(context.Context, *Version, int) error) *Mockdber_snapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// start provides a mock function with given fields: ctx, s
+func (_m *Mockdber) start(ctx context.Context, s *Session) error {
+	ret := _m.Called(ctx, s)
+
+	if len(ret) == 0 {
+		panic("no return value specified for start")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Session) error); ok {
+		r0 = rf(ctx, s)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_start_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'start'
+type Mockdber_start_Call struct {
+	*mock.Call
+}
+
+// start is a helper method to define mock.On call
+//   - ctx context.Context
+//   - s *Session
+func (_e *Mockdber_Expecter) start(ctx interface{}, s interface{}) *Mockdber_start_Call {
+	return &Mockdber_start_Call{Call: _e.mock.On("start", ctx, s)}
+}
+
+func (_c *Mockdber_start_Call) Run(run func(ctx context.Context, s *Session)) *Mockdber_start_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Session))
+	})
+	return _c
+}
+
+func (_c *Mockdber_start_Call) Return(_a0 error) *Mockdber_start_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_start_Call) RunAndReturn(run func(context.Context, *Session) error) *Mockdber_start_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// stats provides a mock function with given fields: ctx, q, limit
+func (_m *Mockdber) stats(ctx context.Context, q historyQuery, limit int) (*Stats, error) {
+	ret := _m.Called(ctx, q, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for stats")
+	}
+
+	var r0 *Stats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, historyQuery, int) (*Stats, error)); ok {
+		return rf(ctx, q, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, historyQuery, int) *Stats); ok {
+		r0 = rf(ctx, q, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Stats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, historyQuery, int) error); ok {
+		r1 = rf(ctx, q, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_stats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'stats'
+type Mockdber_stats_Call struct {
+	*mock.Call
+}
+
+// stats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - q historyQuery
+//   - limit int
+func (_e *Mockdber_Expecter) stats(ctx interface{}, q interface{}, limit interface{}) *Mockdber_stats_Call {
+	return &Mockdber_stats_Call{Call: _e.mock.On("stats", ctx, q, limit)}
+}
+
+func (_c *Mockdber_stats_Call) Run(run func(ctx context.Context, q historyQuery, limit int)) *Mockdber_stats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(historyQuery), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Mockdber_stats_Call) Return(_a0 *Stats, _a1 error) *Mockdber_stats_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_stats_Call) RunAndReturn(run func(context.Context, historyQuery, int) (*Stats, error)) *Mockdber_stats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// stock provides a mock function with given fields: ctx, charts
+func (_m *Mockdber) stock(ctx context.Context, charts []*Chart) error {
+	ret := _m.Called(ctx, charts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for stock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*Chart) error); ok {
+		r0 = rf(ctx, charts)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_stock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'stock'
+type Mockdber_stock_Call struct {
+	*mock.Call
+}
+
+// stock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - charts []*Chart
+func (_e *Mockdber_Expecter) stock(ctx interface{}, charts interface{}) *Mockdber_stock_Call {
+	return &Mockdber_stock_Call{Call: _e.mock.On("stock", ctx, charts)}
+}
+
+func (_c *Mockdber_stock_Call) Run(run func(ctx context.Context, charts []*Chart)) *Mockdber_stock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]*Chart))
+	})
+	return _c
+}
+
+func (_c *Mockdber_stock_Call) Return(_a0 error) *Mockdber_stock_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_stock_Call) RunAndReturn(run func(context.Context, []*Chart) error) *Mockdber_stock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// stop provides a mock function with given fields: ctx, s
+func (_m *Mockdber) stop(ctx context.Context, s *Session) error {
+	ret := _m.Called(ctx, s)
+
+	if len(ret) == 0 {
+		panic("no return value specified for stop")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *Session) error); ok {
+		r0 = rf(ctx, s)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_stop_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'stop'
+type Mockdber_stop_Call struct {
+	*mock.Call
+}
+
+// stop is a helper method to define mock.On call
+//   - ctx context.Context
+//   - s *Session
+func (_e *Mockdber_Expecter) stop(ctx interface{}, s interface{}) *Mockdber_stop_Call {
+	return &Mockdber_stop_Call{Call: _e.mock.On("stop", ctx, s)}
+}
+
+func (_c *Mockdber_stop_Call) Run(run func(ctx context.Context, s *Session)) *Mockdber_stop_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*Session))
+	})
+	return _c
+}
+
+func (_c *Mockdber_stop_Call) Return(_a0 error) *Mockdber_stop_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_stop_Call) RunAndReturn(run func(context.Context, *Session) error) *Mockdber_stop_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// submit provides a mock function with given fields: ctx, r
+func (_m *Mockdber) submit(ctx context.Context, r *PendingRequest) error {
+	ret := _m.Called(ctx, r)
+
+	if len(ret) == 0 {
+		panic("no return value specified for submit")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *PendingRequest) error); ok {
+		r0 = rf(ctx, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_submit_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'submit'
+type Mockdber_submit_Call struct {
+	*mock.Call
+}
+
+// submit is a helper method to define mock.On call
+//   - ctx context.Context
+//   - r *PendingRequest
+func (_e *Mockdber_Expecter) submit(ctx interface{}, r interface{}) *Mockdber_submit_Call {
+	return &Mockdber_submit_Call{Call: _e.mock.On("submit", ctx, r)}
+}
+
+func (_c *Mockdber_submit_Call) Run(run func(ctx context.Context, r *PendingRequest)) *Mockdber_submit_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*PendingRequest))
+	})
+	return _c
+}
+
+func (_c *Mockdber_submit_Call) Return(_a0 error) *Mockdber_submit_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_submit_Call) RunAndReturn(run func(context.Context, *PendingRequest) error) *Mockdber_submit_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// tag provides a mock function with given fields: ctx, name, tags
+func (_m *Mockdber) tag(ctx context.Context, name string, tags []string) error {
+	ret := _m.Called(ctx, name, tags)
+
+	if len(ret) == 0 {
+		panic("no return value specified for tag")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, name, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_tag_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'tag'
+type Mockdber_tag_Call struct {
+	*mock.Call
+}
+
+// tag is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - tags []string
+func (_e *Mockdber_Expecter) tag(ctx interface{}, name interface{}, tags interface{}) *Mockdber_tag_Call {
+	return &Mockdber_tag_Call{Call: _e.mock.On("tag", ctx, name, tags)}
+}
+
+func (_c *Mockdber_tag_Call) Run(run func(ctx context.Context, name string, tags []string)) *Mockdber_tag_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].([]string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_tag_Call) Return(_a0 error) *Mockdber_tag_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_tag_Call) RunAndReturn(run func(context.Context, string, []string) error) *Mockdber_tag_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// target provides a mock function with given fields: ctx, name, target, policy
+func (_m *Mockdber) target(ctx context.Context, name string, target int, policy targetPolicy) error {
+	ret := _m.Called(ctx, name, target, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for target")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, targetPolicy) error); ok {
+		r0 = rf(ctx, name, target, policy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Mockdber_target_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'target'
+type Mockdber_target_Call struct {
+	*mock.Call
+}
+
+// target is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+//   - target int
+//   - policy targetPolicy
+func (_e *Mockdber_Expecter) target(ctx interface{}, name interface{}, target interface{}, policy interface{}) *Mockdber_target_Call {
+	return &Mockdber_target_Call{Call: _e.mock.On("target", ctx, name, target, policy)}
+}
+
+func (_c *Mockdber_target_Call) Run(run func(ctx context.Context, name string, target int, policy targetPolicy)) *Mockdber_target_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(int), args[3].(targetPolicy))
+	})
+	return _c
+}
+
+func (_c *Mockdber_target_Call) Return(_a0 error) *Mockdber_target_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Mockdber_target_Call) RunAndReturn(run func(context.Context, string, int, targetPolicy) error) *Mockdber_target_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// trashed provides a mock function with given fields: ctx
+func (_m *Mockdber) trashed(ctx context.Context) ([]*TrashedSetlist, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for trashed")
+	}
+
+	var r0 []*TrashedSetlist
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*TrashedSetlist, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*TrashedSetlist); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*TrashedSetlist)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_trashed_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'trashed'
+type Mockdber_trashed_Call struct {
+	*mock.Call
+}
+
+// trashed is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Mockdber_Expecter) trashed(ctx interface{}) *Mockdber_trashed_Call {
+	return &Mockdber_trashed_Call{Call: _e.mock.On("trashed", ctx)}
+}
+
+func (_c *Mockdber_trashed_Call) Run(run func(ctx context.Context)) *Mockdber_trashed_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Mockdber_trashed_Call) Return(_a0 []*TrashedSetlist, _a1 error) *Mockdber_trashed_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_trashed_Call) RunAndReturn(run func(context.Context) ([]*TrashedSetlist, error)) *Mockdber_trashed_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// untrash provides a mock function with given fields: ctx, id, name
+func (_m *Mockdber) untrash(ctx context.Context, id string, name string) (*Setlist, error) {
+	ret := _m.Called(ctx, id, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for untrash")
+	}
+
+	var r0 *Setlist
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*Setlist, error)); ok {
+		return rf(ctx, id, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Setlist); ok {
+		r0 = rf(ctx, id, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Setlist)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, id, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_untrash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'untrash'
+type Mockdber_untrash_Call struct {
+	*mock.Call
+}
+
+// untrash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - name string
+func (_e *Mockdber_Expecter) untrash(ctx interface{}, id interface{}, name interface{}) *Mockdber_untrash_Call {
+	return &Mockdber_untrash_Call{Call: _e.mock.On("untrash", ctx, id, name)}
+}
+
+func (_c *Mockdber_untrash_Call) Run(run func(ctx context.Context, id string, name string)) *Mockdber_untrash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_untrash_Call) Return(_a0 *Setlist, _a1 error) *Mockdber_untrash_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_untrash_Call) RunAndReturn(run func(context.Context, string, string) (*Setlist, error)) *Mockdber_untrash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// update provides a mock function with given fields: ctx, oldName, newName
+func (_m *Mockdber) update(ctx context.Context, oldName string, newName string) (*Setlist, error) {
+	ret := _m.Called(ctx, oldName, newName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for update")
+	}
+
+	var r0 *Setlist
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*Setlist, error)); ok {
+		return rf(ctx, oldName, newName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *Setlist); ok {
+		r0 = rf(ctx, oldName, newName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Setlist)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, oldName, newName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'update'
+type Mockdber_update_Call struct {
+	*mock.Call
+}
+
+// update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - oldName string
+//   - newName string
+func (_e *Mockdber_Expecter) update(ctx interface{}, oldName interface{}, newName interface{}) *Mockdber_update_Call {
+	return &Mockdber_update_Call{Call: _e.mock.On("update", ctx, oldName, newName)}
+}
+
+func (_c *Mockdber_update_Call) Run(run func(ctx context.Context, oldName string, newName string)) *Mockdber_update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_update_Call) Return(_a0 *Setlist, _a1 error) *Mockdber_update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_update_Call) RunAndReturn(run func(context.Context, string, string) (*Setlist, error)) *Mockdber_update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// versions provides a mock function with given fields: ctx, name
+func (_m *Mockdber) versions(ctx context.Context, name string) ([]*Version, error) {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for versions")
+	}
+
+	var r0 []*Version
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*Version, error)); ok {
+		return rf(ctx, name)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*Version); ok {
+		r0 = rf(ctx, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Version)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mockdber_versions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'versions'
+type Mockdber_versions_Call struct {
+	*mock.Call
+}
+
+// versions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - name string
+func (_e *Mockdber_Expecter) versions(ctx interface{}, name interface{}) *Mockdber_versions_Call {
+	return &Mockdber_versions_Call{Call: _e.mock.On("versions", ctx, name)}
+}
+
+func (_c *Mockdber_versions_Call) Run(run func(ctx context.Context, name string)) *Mockdber_versions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Mockdber_versions_Call) Return(_a0 []*Version, _a1 error) *Mockdber_versions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Mockdber_versions_Call) RunAndReturn(run func(context.Context, string) ([]*Version, error)) *Mockdber_versions_Call {
 	_c.Call.Return(run)
 	return _c
 }
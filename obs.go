@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+const (
+	obsNowPlayingFile = "nowplaying.txt"
+	obsNextFile       = "next.txt"
+	obsQueueFile      = "queue.txt"
+
+	defaultOBSSongFormat  = "{{.Artist}} - {{.Name}}"
+	defaultOBSQueueFormat = "{{.Position}}. {{.Artist}} - {{.Name}}"
+)
+
+// obsSink writes the temporary setlist's currently playing song, the song after it and
+// the rest of the queue to text files that can be displayed with OBS "Read from file"
+// text sources. It is only useful when songvoyage runs on the same machine as OBS, so only
+// one streamer's setlist is written.
+type obsSink struct {
+	dir string
+	// streamer is the streamer whose temporary setlist is written.
+	streamer   string
+	nowPlaying *template.Template
+	next       *template.Template
+	queue      *template.Template
+}
+
+// obsSong is the data provided to each file's format template. Position is the song's
+// 1-based position within the file being written.
+type obsSong struct {
+	Position int
+	*Song
+}
+
+// newOBSSink returns a new instance of obsSink writing the streamer's temporary setlist to
+// the provided directory. Each format is a text/template rendered once per song written to
+// its file.
+func newOBSSink(dir, streamer, nowPlayingFormat, nextFormat, queueFormat string) (*obsSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+
+	o := &obsSink{dir: dir, streamer: streamer}
+	for _, f := range []struct {
+		tmpl   **template.Template
+		name   string
+		format string
+	}{
+		{&o.nowPlaying, obsNowPlayingFile, nowPlayingFormat},
+		{&o.next, obsNextFile, nextFormat},
+		{&o.queue, obsQueueFile, queueFormat},
+	} {
+		t, err := template.New(f.name).Parse(f.format)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s format: %w", f.name, err)
+		}
+		*f.tmpl = t
+	}
+
+	return o, nil
+}
+
+// notify rewrites each of the files from the provided setlist. Only the sink's streamer's
+// temporary setlist is written since it is the one being played from during a stream.
+func (o *obsSink) notify(ctx context.Context, sl *Setlist) error {
+	if sl == nil || sl.Name != tempSetlistName || streamerFrom(ctx) != o.streamer {
+		return nil
+	}
+
+	var nowPlaying, next, queue []*Song
+	if len(sl.Songs) > 0 {
+		nowPlaying = sl.Songs[:1]
+		queue = sl.Songs[1:]
+	}
+	if len(queue) > 0 {
+		next = queue[:1]
+	}
+
+	for _, f := range []struct {
+		tmpl  *template.Template
+		songs []*Song
+	}{
+		{o.nowPlaying, nowPlaying},
+		{o.next, next},
+		{o.queue, queue},
+	} {
+		b, err := render(f.tmpl, f.songs)
+		if err != nil {
+			return fmt.Errorf("rendering %s: %w", f.tmpl.Name(), err)
+		}
+
+		if err := writeFileAtomic(filepath.Join(o.dir, f.tmpl.Name()), b); err != nil {
+			return fmt.Errorf("writing %s: %w", f.tmpl.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// render executes the template once per song, separating each with a newline.
+func render(t *template.Template, songs []*Song) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, s := range songs {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+
+		if err := t.Execute(&buf, obsSong{Position: i + 1, Song: s}); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as path and then
+// renames it into place so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	// removing after a successful rename is a no-op
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return fmt.Errorf("setting file mode: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOBSSinkNotify(t *testing.T) {
+	songs := []*Song{
+		{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+		{Artist: "Avenged Sevenfold", Name: "Afterlife"},
+		{Artist: "Trivium", Name: "Pull Harder on the Strings of Your Martyr"},
+	}
+
+	testCases := []struct {
+		name        string
+		formats     [3]string
+		streamer    string
+		setlist     *Setlist
+		expected    map[string]string
+		expectedErr string
+	}{
+		{
+			name:    "writes all files with default formats",
+			formats: [3]string{defaultOBSSongFormat, defaultOBSSongFormat, defaultOBSQueueFormat},
+			setlist: &Setlist{Name: tempSetlistName, Songs: songs},
+			expected: map[string]string{
+				obsNowPlayingFile: "Dragonforce - Through the Fire and Flames",
+				obsNextFile:       "Avenged Sevenfold - Afterlife",
+				obsQueueFile: "1. Avenged Sevenfold - Afterlife\n" +
+					"2. Trivium - Pull Harder on the Strings of Your Martyr",
+			},
+		},
+		{
+			name:    "writes files with custom formats",
+			formats: [3]string{"Now: {{.Name}}", "Up next: {{.Name}} by {{.Artist}}", "#{{.Position}} {{.Name}}"},
+			setlist: &Setlist{Name: tempSetlistName, Songs: songs[:2]},
+			expected: map[string]string{
+				obsNowPlayingFile: "Now: Through the Fire and Flames",
+				obsNextFile:       "Up next: Afterlife by Avenged Sevenfold",
+				obsQueueFile:      "#1 Afterlife",
+			},
+		},
+		{
+			name:    "empties files when setlist has no songs",
+			formats: [3]string{defaultOBSSongFormat, defaultOBSSongFormat, defaultOBSQueueFormat},
+			setlist: &Setlist{Name: tempSetlistName},
+			expected: map[string]string{
+				obsNowPlayingFile: "",
+				obsNextFile:       "",
+				obsQueueFile:      "",
+			},
+		},
+		{
+			name:     "ignores persisted setlists",
+			formats:  [3]string{defaultOBSSongFormat, defaultOBSSongFormat, defaultOBSQueueFormat},
+			setlist:  &Setlist{Name: "Doomed Fingers", Songs: songs},
+			expected: map[string]string{},
+		},
+		{
+			name:     "ignores other streamers' setlists",
+			formats:  [3]string{defaultOBSSongFormat, defaultOBSSongFormat, defaultOBSQueueFormat},
+			streamer: "other_streamer",
+			setlist:  &Setlist{Name: tempSetlistName, Songs: songs},
+			expected: map[string]string{},
+		},
+		{
+			name:        "errors when format is invalid",
+			formats:     [3]string{"{{.Artist", defaultOBSSongFormat, defaultOBSQueueFormat},
+			expectedErr: "parsing nowplaying.txt format",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			o, err := newOBSSink(dir, "doomedfingers", tc.formats[0], tc.formats[1], tc.formats[2])
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			streamer := cmp.Or(tc.streamer, "doomedfingers")
+			require.NoError(t, o.notify(withStreamer(context.Background(), streamer), tc.setlist))
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, len(tc.expected), "no temporary files should be left behind")
+			for file, expected := range tc.expected {
+				b, err := os.ReadFile(filepath.Join(dir, file))
+				require.NoError(t, err)
+				assert.Equal(t, expected, string(b))
+			}
+		})
+	}
+}
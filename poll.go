@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// pollSource determines where a poll's candidate songs come from.
+type pollSource string
+
+const (
+	// pollSourceQueue picks candidates from the songs on the temporary setlist.
+	pollSourceQueue pollSource = "queue"
+	// pollSourceRandom picks candidates at random from the streamer's library.
+	pollSourceRandom pollSource = "random"
+
+	defaultPollSize    = 3
+	maxPollSize        = 10
+	defaultPollSeconds = 60
+)
+
+// errPollOpen is returned when opening a poll while the streamer already has one open.
+var errPollOpen = errors.New("a poll is already open")
+
+// poll is a timed vote among viewers on the song to play next. Each viewer gets a single
+// vote, cast by the candidate's number.
+type poll struct {
+	Source     pollSource `json:"source"`
+	Candidates []*Song    `json:"candidates"`
+	// Tally is the number of votes for each candidate, in the same order as Candidates.
+	Tally    []int     `json:"tally"`
+	ClosesAt time.Time `json:"closes_at"`
+	Closed   bool      `json:"closed"`
+	// Winner is the candidate with the most votes once the poll has closed. Ties go to
+	// the earlier candidate and there is no winner if nobody voted.
+	Winner *Song `json:"winner,omitempty"`
+
+	voters map[string]bool
+	timer  *time.Timer
+}
+
+// snapshot returns a copy of the poll that is safe to use without holding the lock.
+func (p *poll) snapshot() *poll {
+	return &poll{
+		Source:     p.Source,
+		Candidates: p.Candidates,
+		Tally:      append([]int(nil), p.Tally...),
+		ClosesAt:   p.ClosesAt,
+		Closed:     p.Closed,
+		Winner:     p.Winner,
+	}
+}
+
+// polls keeps each streamer's most recent poll. Since polls only last for a short time
+// they're kept in memory rather than in the db.
+type polls struct {
+	mu         sync.Mutex
+	byStreamer map[string]*poll
+}
+
+func newPolls() *polls {
+	return &polls{byStreamer: map[string]*poll{}}
+}
+
+// open starts a poll over the candidates for the streamer, calling onClose once it has
+// been open for the provided duration.
+func (ps *polls) open(streamer string, source pollSource, candidates []*Song, d time.Duration, onClose func()) (*poll, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if p, ok := ps.byStreamer[streamer]; ok && !p.Closed {
+		return nil, errPollOpen
+	}
+
+	p := &poll{
+		Source:     source,
+		Candidates: candidates,
+		Tally:      make([]int, len(candidates)),
+		ClosesAt:   time.Now().Add(d),
+		voters:     map[string]bool{},
+		timer:      time.AfterFunc(d, onClose),
+	}
+	ps.byStreamer[streamer] = p
+
+	return p.snapshot(), nil
+}
+
+// vote records a viewer's vote for the candidate with the provided 1-based number.
+// Votes that can't be counted are returned as a refusalError.
+func (ps *polls) vote(streamer, voter string, choice int) (*poll, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.byStreamer[streamer]
+	switch {
+	case !ok || p.Closed:
+		return nil, &refusalError{reason: "there is no poll open"}
+	case choice < 1 || choice > len(p.Candidates):
+		return nil, &refusalError{reason: fmt.Sprintf("vote with a number from 1 to %d", len(p.Candidates))}
+	case p.voters[voter]:
+		return nil, &refusalError{reason: fmt.Sprintf("%s has already voted", voter)}
+	}
+
+	p.voters[voter] = true
+	p.Tally[choice-1]++
+
+	return p.snapshot(), nil
+}
+
+// current returns the streamer's most recent poll or nil if they haven't had one.
+func (ps *polls) current(streamer string) *poll {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.byStreamer[streamer]
+	if !ok {
+		return nil
+	}
+
+	return p.snapshot()
+}
+
+// close closes the streamer's open poll, picking its winner. It returns nil if the
+// streamer has no open poll, e.g. when it has already been closed early.
+func (ps *polls) close(streamer string) *poll {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.byStreamer[streamer]
+	if !ok || p.Closed {
+		return nil
+	}
+
+	p.timer.Stop()
+	p.Closed = true
+	p.ClosesAt = time.Now()
+
+	best := 0
+	for i, n := range p.Tally {
+		if n > best {
+			best = n
+			p.Winner = p.Candidates[i]
+		}
+	}
+
+	return p.snapshot()
+}
+
+// pollCandidates picks up to size songs to vote on. Candidates from the queue are taken
+// from the temporary setlist, leaving out the song being played, while random candidates
+// are taken from the library, leaving out songs already on the temporary setlist.
+func pollCandidates(source pollSource, temp *Setlist, library []*Chart, size int, rnd *rand.Rand) []*Song {
+	var queued []*Song
+	if temp != nil {
+		queued = temp.Songs
+	}
+
+	var pool []*Song
+	switch source {
+	case pollSourceQueue:
+		if len(queued) > 1 {
+			pool = append(pool, queued[1:]...)
+		}
+	case pollSourceRandom:
+		seen := map[string]bool{}
+		for _, s := range queued {
+			seen[songKey(s)] = true
+		}
+		for _, c := range library {
+			if k := songKey(&c.Song); !seen[k] {
+				seen[k] = true
+				pool = append(pool, &c.Song)
+			}
+		}
+	}
+
+	rnd.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	return pool[:min(len(pool), size)]
+}
+
+// promote moves the song to the top of the temporary setlist's queue, right after the song
+// being played, adding it first if it isn't already queued. Adding the song follows the
+// same rules as any other song being added, so it may be refused.
+func promote(ctx context.Context, db songAdder, s *Song, gap int) error {
+	position, err := queuedPosition(ctx, db, s)
+	if err != nil {
+		return err
+	}
+
+	if position == 0 {
+		if _, _, err := addSong(ctx, db, &songRequest{Artist: s.Artist, Song: s.Name, Gap: gap}); err != nil {
+			return err
+		}
+		if position, err = queuedPosition(ctx, db, s); err != nil {
+			return err
+		}
+	}
+
+	if position > 2 {
+		if err := db.move(ctx, tempSetlistName, position, 2); err != nil {
+			return fmt.Errorf("moving song: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// queuedPosition returns the 1-based position of the song in the temporary setlist's queue
+// or zero if it isn't queued. The song being played at the top of the setlist isn't part of
+// the queue.
+func queuedPosition(ctx context.Context, db finder, s *Song) (int, error) {
+	sl, err := db.find(ctx, tempSetlistName)
+	if err != nil {
+		return 0, fmt.Errorf("looking up setlist: %w", err)
+	}
+	if sl == nil {
+		return 0, nil
+	}
+
+	for i, song := range sl.Songs {
+		if i > 0 && songKey(song) == songKey(s) {
+			return i + 1, nil
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolls(t *testing.T) {
+	candidates := []*Song{
+		{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+		{Artist: "Trivium", Name: "Down From the Sky"},
+		{Artist: "Sum 41", Name: "Fat Lip"},
+	}
+
+	testCases := []struct {
+		name     string
+		votes    map[string]int
+		expected *Song
+	}{
+		{
+			name:     "most votes wins",
+			votes:    map[string]int{"a": 3, "b": 2, "c": 3},
+			expected: candidates[2],
+		},
+		{
+			name:     "ties go to the earlier candidate",
+			votes:    map[string]int{"a": 3, "b": 2},
+			expected: candidates[1],
+		},
+		{
+			name: "no winner without votes",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := newPolls()
+
+			_, err := ps.open("streamer", pollSourceQueue, candidates, time.Hour, func() {})
+			require.NoError(t, err)
+
+			for voter, choice := range tc.votes {
+				_, err := ps.vote("streamer", voter, choice)
+				require.NoError(t, err)
+			}
+
+			p := ps.close("streamer")
+			require.NotNil(t, p)
+			assert.True(t, p.Closed)
+			assert.Equal(t, tc.expected, p.Winner)
+
+			// closing again is a no-op
+			assert.Nil(t, ps.close("streamer"))
+		})
+	}
+
+	t.Run("refuses bad votes", func(t *testing.T) {
+		ps := newPolls()
+
+		_, err := ps.vote("streamer", "a", 1)
+		assert.ErrorAs(t, err, new(*refusalError))
+
+		_, err = ps.open("streamer", pollSourceQueue, candidates, time.Hour, func() {})
+		require.NoError(t, err)
+		_, err = ps.open("streamer", pollSourceQueue, candidates, time.Hour, func() {})
+		assert.ErrorIs(t, err, errPollOpen)
+
+		_, err = ps.vote("streamer", "a", 4)
+		assert.ErrorAs(t, err, new(*refusalError))
+
+		p, err := ps.vote("streamer", "a", 1)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 0, 0}, p.Tally)
+
+		_, err = ps.vote("streamer", "a", 2)
+		assert.ErrorAs(t, err, new(*refusalError))
+
+		// polls are kept apart per streamer
+		_, err = ps.vote("other", "a", 1)
+		assert.ErrorAs(t, err, new(*refusalError))
+	})
+
+	t.Run("closes once time is up", func(t *testing.T) {
+		ps := newPolls()
+		closed := make(chan *poll)
+
+		_, err := ps.open("streamer", pollSourceQueue, candidates, time.Millisecond, func() {
+			closed <- ps.close("streamer")
+		})
+		require.NoError(t, err)
+
+		select {
+		case p := <-closed:
+			require.NotNil(t, p)
+			assert.True(t, p.Closed)
+		case <-time.After(time.Second):
+			t.Fatal("poll wasn't closed")
+		}
+	})
+}
+
+func TestPromote(t *testing.T) {
+	testCases := []struct {
+		name string
+		song *Song
+		db   func(t *testing.T) *Mockdber
+	}{
+		{
+			name: "moves queued song to top of queue",
+			song: &Song{Artist: "Sum 41", Name: "Fat Lip"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name: tempSetlistName,
+					Songs: []*Song{
+						{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+						{Artist: "Trivium", Name: "Down From the Sky"},
+						{Artist: "Sum 41", Name: "Fat Lip"},
+					},
+				}, nil)
+				db.On("move", mock.Anything, tempSetlistName, 3, 2).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name: "adds song that isn't queued before moving it",
+			song: &Song{Artist: "Sum 41", Name: "Fat Lip"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				queued := &Setlist{
+					Name: tempSetlistName,
+					Songs: []*Song{
+						{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+						{Artist: "Trivium", Name: "Down From the Sky"},
+					},
+				}
+				db.On("find", mock.Anything, tempSetlistName).Return(queued, nil).Twice()
+				db.On("library", mock.Anything).Return(nil, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("add", mock.Anything, tempSetlistName, &Song{Artist: "Sum 41", Name: "Fat Lip"}).Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name:  tempSetlistName,
+					Songs: append(queued.Songs, &Song{Artist: "Sum 41", Name: "Fat Lip"}),
+				}, nil)
+				db.On("move", mock.Anything, tempSetlistName, 3, 2).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name: "leaves song being played in place",
+			song: &Song{Artist: "Sum 41", Name: "Fat Lip"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name: tempSetlistName,
+					Songs: []*Song{
+						{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+						{Artist: "Sum 41", Name: "Fat Lip"},
+					},
+				}, nil)
+
+				return db
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, promote(context.Background(), tc.db(t), tc.song, 0))
+		})
+	}
+}
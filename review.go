@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PendingRequest is a viewer's request waiting for review by a streamer's mods.
+type PendingRequest struct {
+	ID string `json:"id"`
+	// Song is the requested song, with its details filled in from the streamer's library and
+	// the viewer who requested it.
+	Song        *Song     `json:"song"`
+	RequestedAt time.Time `json:"requested_at"`
+	// Override is whether the request skips the streamer's replay cooldown, kept so it
+	// still does once approved.
+	Override bool `json:"override,omitempty"`
+}
+
+// requestSong requests a song on the temporary setlist for the viewer named in the request.
+// When the streamer reviews requests, the song is held for review and returned as a
+// pending request instead of being added, unless it's blocked, including for requests
+// that don't name a viewer. Otherwise, and for trusted requests, e.g. mods adding songs
+// directly, it's added the same as with addSong and the song added is returned.
+func requestSong(ctx context.Context, db requester, r *songRequest) (*Song, *PendingRequest, []string, error) {
+	if r.Trusted || setlistName([]byte(r.Setlist)) != tempSetlistName {
+		s, warnings, err := addSong(ctx, db, r)
+		return s, nil, warnings, err
+	}
+
+	if r.Artist == "" || r.Song == "" {
+		return nil, nil, nil, errMissingSong
+	}
+
+	reviewing, err := db.reviewing(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("looking up whether requests are reviewed: %w", err)
+	}
+	if !reviewing {
+		s, warnings, err := addSong(ctx, db, r)
+		return s, nil, warnings, err
+	}
+
+	s := &Song{Artist: r.Artist, Name: r.Song}
+	library, err := db.library(ctx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("looking up library: %w", err)
+	}
+	if c := findChart(library, s); c != nil {
+		details := c.Song
+		s = &details
+	}
+	if err := checkBlocklist(ctx, db, s, r.Override); err != nil {
+		return nil, nil, nil, err
+	}
+	s.Priority = r.Priority
+	s.RequestedBy = r.User
+
+	p := &PendingRequest{
+		ID:          primitive.NewObjectID().Hex(),
+		Song:        s,
+		RequestedAt: time.Now().UTC(),
+		Override:    r.Override,
+	}
+	if err := db.submit(ctx, p); err != nil {
+		return nil, nil, nil, fmt.Errorf("submitting request for review: %w", err)
+	}
+
+	return nil, p, nil, nil
+}
+
+// approve adds a pending request's song to the temporary setlist for its original
+// requester, returning the request or nil if no request with the ID is pending. If adding
+// the song is refused, e.g. for going over the setlist's target, the request is put back up
+// for review.
+func approve(ctx context.Context, db requester, id string, gap int) (*PendingRequest, []string, error) {
+	p, err := db.resolve(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving request: %w", err)
+	}
+	if p == nil {
+		return nil, nil, nil
+	}
+
+	_, warnings, err := addSong(ctx, db, &songRequest{
+		Artist:      p.Song.Artist,
+		Song:        p.Song.Name,
+		Gap:         gap,
+		Priority:    p.Song.Priority,
+		User:        p.Song.RequestedBy,
+		Override:    p.Override,
+		RequestedAt: p.RequestedAt,
+	})
+	if err != nil {
+		if serr := db.submit(ctx, p); serr != nil {
+			return p, nil, fmt.Errorf("putting request back up for review: %w", serr)
+		}
+		return p, nil, err
+	}
+
+	return p, warnings, nil
+}
+
+// rejectionMessage returns the message relayed to chat when a request is rejected.
+func rejectionMessage(p *PendingRequest, reason string) string {
+	msg := fmt.Sprintf("@%s your request for %s was rejected", p.Song.RequestedBy, chatSong(p.Song))
+	if p.Song.RequestedBy == "" {
+		msg = fmt.Sprintf("The request for %s was rejected", chatSong(p.Song))
+	}
+	if reason != "" {
+		msg += ": " + reason
+	}
+
+	return msg
+}
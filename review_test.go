@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSong(t *testing.T) {
+	library := []*Chart{{Song: Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}}}
+
+	testCases := []struct {
+		name            string
+		request         *songRequest
+		db              func(t *testing.T) *Mockdber
+		expectedPending *Song
+	}{
+		{
+			name:    "holds viewer requests for review",
+			request: &songRequest{Artist: "sum 41", Song: "fat lip", User: "cooler_user", Priority: 1},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(true, nil)
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("submit", mock.Anything, mock.MatchedBy(func(p *PendingRequest) bool {
+					return p.ID != "" && !p.RequestedAt.IsZero()
+				})).Return(nil)
+
+				return db
+			},
+			expectedPending: &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180, Priority: 1, RequestedBy: "cooler_user"},
+		},
+		{
+			name:    "adds viewer requests when not reviewing",
+			request: &songRequest{Artist: "Sum 41", Song: "Fat Lip", User: "cooler_user"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+				db.On("add", mock.Anything, tempSetlistName, requested(&Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180, RequestedBy: "cooler_user"})).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name:    "holds requests without a viewer for review",
+			request: &songRequest{Artist: "Sum 41", Song: "Fat Lip"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(true, nil)
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("submit", mock.Anything, mock.AnythingOfType("*main.PendingRequest")).Return(nil)
+
+				return db
+			},
+			expectedPending: &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180},
+		},
+		{
+			name:    "adds trusted requests directly",
+			request: &songRequest{Artist: "Sum 41", Song: "Fat Lip", Trusted: true},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+				db.On("add", mock.Anything, tempSetlistName, &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}).Return(nil)
+
+				return db
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, p, _, err := requestSong(context.Background(), tc.db(t), tc.request)
+			require.NoError(t, err)
+
+			if tc.expectedPending == nil {
+				assert.Nil(t, p)
+				return
+			}
+			require.NotNil(t, p)
+			assert.Equal(t, tc.expectedPending, p.Song)
+		})
+	}
+}
+
+func TestApprove(t *testing.T) {
+	pending := func() *PendingRequest {
+		return &PendingRequest{
+			ID:   "65f0c0ffee",
+			Song: &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180, Priority: 1, RequestedBy: "cooler_user"},
+		}
+	}
+
+	t.Run("adds song for its requester", func(t *testing.T) {
+		db := NewMockdber(t)
+		db.On("resolve", mock.Anything, "65f0c0ffee").Return(pending(), nil)
+		db.On("library", mock.Anything).Return([]*Chart{}, nil)
+		db.On("blocklist", mock.Anything).Return(nil, nil)
+		db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+		db.On("add", mock.Anything, tempSetlistName, requested(&Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 1, RequestedBy: "cooler_user"})).Return(nil)
+
+		p, _, err := approve(context.Background(), db, "65f0c0ffee", 30)
+		require.NoError(t, err)
+		assert.Equal(t, pending(), p)
+	})
+
+	t.Run("keeps request's time", func(t *testing.T) {
+		requestedAt := time.Date(2024, 3, 12, 20, 15, 0, 0, time.UTC)
+		p := pending()
+		p.RequestedAt = requestedAt
+
+		db := NewMockdber(t)
+		db.On("resolve", mock.Anything, "65f0c0ffee").Return(p, nil)
+		db.On("library", mock.Anything).Return([]*Chart{}, nil)
+		db.On("blocklist", mock.Anything).Return(nil, nil)
+		db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+		db.On("add", mock.Anything, tempSetlistName, &Song{
+			Artist:      "Sum 41",
+			Name:        "Fat Lip",
+			Priority:    1,
+			RequestedBy: "cooler_user",
+			RequestedAt: &requestedAt,
+		}).Return(nil)
+
+		_, _, err := approve(context.Background(), db, "65f0c0ffee", 30)
+		require.NoError(t, err)
+	})
+
+	t.Run("keeps request's cooldown override", func(t *testing.T) {
+		p := pending()
+		p.Override = true
+
+		db := NewMockdber(t)
+		db.On("resolve", mock.Anything, "65f0c0ffee").Return(p, nil)
+		db.On("library", mock.Anything).Return([]*Chart{}, nil)
+		db.On("blocklist", mock.Anything).Return(&Blocklist{Cooldown: &Cooldown{Minutes: 60}}, nil)
+		db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+		db.On("add", mock.Anything, tempSetlistName, requested(&Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 1, RequestedBy: "cooler_user"})).Return(nil)
+
+		_, _, err := approve(context.Background(), db, "65f0c0ffee", 30)
+		require.NoError(t, err)
+	})
+
+	t.Run("puts refused request back up for review", func(t *testing.T) {
+		db := NewMockdber(t)
+		db.On("resolve", mock.Anything, "65f0c0ffee").Return(pending(), nil)
+		db.On("library", mock.Anything).Return([]*Chart{{Song: Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}}}, nil)
+		db.On("blocklist", mock.Anything).Return(nil, nil)
+		db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+			Name:         tempSetlistName,
+			Target:       60,
+			TargetPolicy: targetPolicyRefuse,
+		}, nil)
+		db.On("submit", mock.Anything, pending()).Return(nil)
+
+		_, _, err := approve(context.Background(), db, "65f0c0ffee", 30)
+		assert.ErrorAs(t, err, new(*refusalError))
+	})
+
+	t.Run("returns nil when request isn't pending", func(t *testing.T) {
+		db := NewMockdber(t)
+		db.On("resolve", mock.Anything, "65f0c0ffee").Return(nil, nil)
+
+		p, _, err := approve(context.Background(), db, "65f0c0ffee", 30)
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+}
+
+// testAnnouncer records the messages it is asked to announce.
+type testAnnouncer struct {
+	messages []string
+}
+
+func (a *testAnnouncer) announce(ctx context.Context, msg string) error {
+	a.messages = append(a.messages, streamerFrom(ctx)+": "+msg)
+	return nil
+}
+
+func TestRejectRequest(t *testing.T) {
+	db := NewMockdber(t)
+	db.On("resolve", mock.Anything, "65f0c0ffee").Return(&PendingRequest{
+		ID:   "65f0c0ffee",
+		Song: &Song{Artist: "Sum 41", Name: "Fat Lip", RequestedBy: "cooler_user"},
+	}, nil).Once()
+	db.On("resolve", mock.Anything, "65f0c0ffee").Return(nil, nil)
+
+	a := &testAnnouncer{}
+	s := newServer()
+	s.db = db
+	s.announcers = []announcer{a}
+	client := newTestServer(t, scopeStreamer(routes(s).Handler))
+
+	resp, err := client.Get(lh + "/v1/review/reject?streamer=doomedfingers&id=65f0c0ffee&reason=played%20it%20already")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{`+
+		`"rejected":{"id":"65f0c0ffee","song":{"artist":"Sum 41","name":"Fat Lip","requested_by":"cooler_user"},"requested_at":"0001-01-01T00:00:00Z"},`+
+		`"reason":"played it already",`+
+		`"message":"@cooler_user your request for Sum 41 - Fat Lip was rejected: played it already"`+
+		`}`, string(body))
+	assert.Equal(t, []string{"doomedfingers: @cooler_user your request for Sum 41 - Fat Lip was rejected: played it already"}, a.messages)
+
+	// rejecting again finds nothing pending
+	resp, err = client.Get(lh + "/v1/review/reject?streamer=doomedfingers&id=65f0c0ffee")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
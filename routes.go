@@ -3,9 +3,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
+	"math/rand/v2"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fasthttp/router"
@@ -25,15 +32,82 @@ func routes(s *server) *router.Router {
 	// existing chat bots, all routes will be accessed via GETs with all data added as
 	// query parameters.
 	slV1.GET("/", s.getSetlist)
+	slV1.GET("/list", s.listSetlists)
 	slV1.GET("/create", s.createSetlist)
 	slV1.GET("/clear", s.clearSetlist)
 	slV1.GET("/save", s.saveSetlist)
 	slV1.GET("/delete", s.deleteSetlist)
+	slV1.GET("/generate", s.generateSetlist)
+	slV1.GET("/copy", s.copySetlist)
+	slV1.GET("/merge", s.mergeSetlist)
+	slV1.GET("/diff", s.diffSetlists)
+	slV1.GET("/export", s.exportSetlist)
+	slV1.GET("/export/game", s.exportGameSetlist)
+	// imports carry their data in the request body, so unlike other routes they're POSTs.
+	slV1.POST("/import", s.importSetlist)
 
 	upV1 := slV1.Group("/update")
 	upV1.GET("/", s.updateSetlist)
 	upV1.GET("/add_song", s.addSong)
 	upV1.GET("/remove_song", s.removeSong)
+	upV1.GET("/target", s.targetSetlist)
+	upV1.GET("/next", s.nextSong)
+	upV1.GET("/tags", s.tagSetlist)
+
+	slV1.GET("/undo", s.undoSetlist)
+	versionsV1 := slV1.Group("/versions")
+	versionsV1.GET("/", s.listVersions)
+	versionsV1.GET("/diff", s.diffVersions)
+	versionsV1.GET("/restore", s.restoreVersion)
+
+	trashV1 := slV1.Group("/trash")
+	trashV1.GET("/", s.listTrash)
+	trashV1.GET("/restore", s.restoreTrash)
+
+	v1.GET("/wheel", s.spinWheel)
+
+	pollV1 := v1.Group("/poll")
+	pollV1.GET("/", s.getPoll)
+	pollV1.GET("/open", s.openPoll)
+	pollV1.GET("/vote", s.votePoll)
+	pollV1.GET("/close", s.closePollEarly)
+
+	reviewV1 := v1.Group("/review")
+	reviewV1.GET("/", s.getReview)
+	reviewV1.GET("/enable", s.enableReview)
+	reviewV1.GET("/approve", s.approveRequest)
+	reviewV1.GET("/reject", s.rejectRequest)
+
+	v1.GET("/stats", s.getStats)
+	v1.GET("/wrapped", s.getWrapped)
+
+	scoresV1 := v1.Group("/scores")
+	scoresV1.GET("/add", s.addScore)
+	scoresV1.GET("/pb", s.personalBest)
+	scoresV1.GET("/progress", s.scoreProgress)
+
+	sessionV1 := v1.Group("/session")
+	sessionV1.GET("/", s.getSession)
+	sessionV1.GET("/list", s.listSessions)
+	sessionV1.GET("/start", s.startSession)
+	sessionV1.GET("/stop", s.stopSession)
+
+	blockV1 := v1.Group("/blocklist")
+	blockV1.GET("/", s.getBlocklist)
+	blockV1.GET("/add", s.addBlock)
+	blockV1.GET("/remove", s.removeBlock)
+	blockV1.GET("/limits", s.limitBlocklist)
+	blockV1.GET("/cooldown", s.setCooldown)
+
+	libV1 := v1.Group("/library")
+	libV1.GET("/", s.getLibrary)
+	libV1.POST("/", s.stockLibrary)
+
+	// webhooks are sent by external services rather than chat bots, so they're POSTs
+	// carrying their events in the request body.
+	r.POST("/webhooks/tips", s.tipWebhook)
+	r.POST("/webhooks/twitch/eventsub", s.twitchEventSub)
+	r.POST("/webhooks/discord/interactions", s.discordInteractions)
 
 	return r
 }
@@ -57,20 +131,335 @@ func (s *server) getSetlist(rctx *fasthttp.RequestCtx) {
 		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
 		return
 	}
+	if sl != nil {
+		sl.summarize(s.songGap)
+	}
 
-	b, err := json.Marshal(sl)
+	writeJSON(rctx, action, sl)
+}
+
+// listSetlists handles requests to list the streamer's persisted setlists a page at a
+// time. Setlists can be searched for by the start of their names with prefix and filtered
+// with tag, repeated to require several tags. sort orders them by name, created, updated
+// or size, with order set to asc or desc.
+func (s *server) listSetlists(rctx *fasthttp.RequestCtx) {
+	action := "list setlists"
+
+	q, err := parseListQuery(rctx.QueryArgs())
 	if err != nil {
-		log.Printf("%s - marshalling setlist data: %s", action, err)
-		rctx.Error(fmt.Sprintf("preparing response data: %s", err), http.StatusInternalServerError)
+		writeError(rctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// TODO: Determine a good way of formatting data for output to chat.
-	if _, err := rctx.Write(b); err != nil {
-		log.Printf("%s - writing response: %s", action, err)
-		rctx.Error(fmt.Sprintf("preparing response data: %s", err), http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	setlists, total, err := s.db.list(ctx, q)
+	if err != nil {
+		log.Printf("%s - listing setlists: %s", action, err)
+		rctx.Error(`{"error":"failed to list setlists"}`, http.StatusInternalServerError)
+		return
+	}
+	if setlists == nil {
+		setlists = []*SetlistListing{}
+	}
+
+	writeJSON(rctx, action, struct {
+		Setlists []*SetlistListing `json:"setlists"`
+		Total    int               `json:"total"`
+		Page     int               `json:"page"`
+		PerPage  int               `json:"per_page"`
+	}{setlists, total, q.Offset/q.Limit + 1, q.Limit})
+}
+
+// tagSetlist handles requests to replace a persisted setlist's tags with tags, a comma
+// separated list. Tags are matched regardless of case, and leaving them out removes the
+// setlist's tags.
+func (s *server) tagSetlist(rctx *fasthttp.RequestCtx) {
+	action := "tag setlist"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+
+	if len(name) == 0 || string(name) == tempSetlistName {
+		rctx.Error(`{"error":"a setlist name is required"}`, http.StatusBadRequest)
+		return
+	}
+	tags := normalizeTags(strings.Split(string(args.Peek("tags")), ","))
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	sl, err := s.db.find(ctx, string(name))
+	if err != nil {
+		log.Printf("%s - getting setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if sl == nil {
+		rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if err := s.db.tag(ctx, string(name), tags); err != nil {
+		log.Printf("%s - tagging setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to tag setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, name)
+}
+
+// copySetlist handles requests to duplicate a setlist under the name provided by to,
+// including its tags and target. If no name is provided the temporary setlist is copied.
+// Copying over an existing setlist is refused.
+func (s *server) copySetlist(rctx *fasthttp.RequestCtx) {
+	action := "copy setlist"
+	args := rctx.QueryArgs()
+	name, to := setlistName(args.Peek("name")), args.Peek("to")
+
+	if len(to) == 0 || string(to) == tempSetlistName {
+		rctx.Error(`{"error":"a setlist name to copy to is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	err := copySetlist(ctx, s.db, name, string(to))
+	switch {
+	case errors.Is(err, errSetlistNotFound):
+		rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errSetlistExists):
+		rctx.Error(`{"error":"a setlist with that name already exists"}`, http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("%s - copying setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to copy setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, to)
+}
+
+// mergeSetlist handles requests to add the songs of one setlist to the end of another,
+// named by into. Songs in both setlists are handled according to the dedupe policy: none
+// (default) keeps every song, first leaves out songs already in the setlist and last moves
+// them to where they are in the setlist merged in. If either name isn't provided the
+// temporary setlist is used, e.g. to queue a persisted setlist.
+func (s *server) mergeSetlist(rctx *fasthttp.RequestCtx) {
+	action := "merge setlist"
+	args := rctx.QueryArgs()
+	name, into := setlistName(args.Peek("name")), args.Peek("into")
+
+	if name == setlistName(into) {
+		rctx.Error(`{"error":"a setlist can't be merged into itself"}`, http.StatusBadRequest)
+		return
+	}
+	policy := dedupePolicy(args.Peek("dedupe"))
+	switch policy {
+	case "":
+		policy = dedupeNone
+	case dedupeNone, dedupeFirst, dedupeLast:
+	default:
+		rctx.Error(`{"error":"dedupe must be one of none, first or last"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	err := mergeSetlists(ctx, s.db, name, setlistName(into), policy)
+	if errors.Is(err, errSetlistNotFound) {
+		rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("%s - merging setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to merge setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, into)
+}
+
+// diffSetlists handles requests to compare two setlists, showing the songs added, removed
+// and moved going from the setlist named by from to the one named by to. If either name
+// isn't provided the temporary setlist is used.
+func (s *server) diffSetlists(rctx *fasthttp.RequestCtx) {
+	action := "diff setlists"
+	args := rctx.QueryArgs()
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	var setlists [2]*Setlist
+	for i, name := range [][]byte{args.Peek("from"), args.Peek("to")} {
+		sl, err := setlist(ctx, s.db, name)
+		if err != nil {
+			log.Printf("%s - getting setlist: %s", action, err)
+			rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+			return
+		}
+		if sl == nil {
+			writeError(rctx, http.StatusNotFound, fmt.Sprintf("setlist %q not found", setlistName(name)))
+			return
+		}
+		setlists[i] = sl
+	}
+
+	writeJSON(rctx, action, diffSongs(setlists[0].Songs, setlists[1].Songs))
+}
+
+// listVersions handles requests to list the versions kept of a setlist, most recent first.
+// If no name is provided the temporary setlist's versions are listed.
+func (s *server) listVersions(rctx *fasthttp.RequestCtx) {
+	action := "list versions"
+	name := setlistName(rctx.QueryArgs().Peek("name"))
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	vs, err := s.db.versions(ctx, name)
+	if err != nil {
+		log.Printf("%s - getting versions: %s", action, err)
+		rctx.Error(`{"error":"failed to get versions"}`, http.StatusInternalServerError)
+		return
+	}
+	if vs == nil {
+		vs = []*Version{}
+	}
+
+	writeJSON(rctx, action, vs)
+}
+
+// diffVersions handles requests to compare two versions of a setlist, showing the songs
+// added, removed and moved going from the version with the from ID to the one with the to
+// ID. When to isn't provided the setlist's current songs are compared against instead.
+func (s *server) diffVersions(rctx *fasthttp.RequestCtx) {
+	action := "diff versions"
+	args := rctx.QueryArgs()
+	name := setlistName(args.Peek("name"))
+	from, to := string(args.Peek("from")), string(args.Peek("to"))
+
+	if from == "" {
+		rctx.Error(`{"error":"a version to diff from is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	older, err := findVersion(ctx, s.db, name, from)
+	if errors.Is(err, errVersionNotFound) {
+		rctx.Error(`{"error":"version not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("%s - getting version: %s", action, err)
+		rctx.Error(`{"error":"failed to get version"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var songs []*Song
+	if to != "" {
+		newer, err := findVersion(ctx, s.db, name, to)
+		if errors.Is(err, errVersionNotFound) {
+			rctx.Error(`{"error":"version not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("%s - getting version: %s", action, err)
+			rctx.Error(`{"error":"failed to get version"}`, http.StatusInternalServerError)
+			return
+		}
+		songs = newer.Songs
+	} else {
+		sl, err := s.db.find(ctx, name)
+		if err != nil {
+			log.Printf("%s - getting setlist: %s", action, err)
+			rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+			return
+		}
+		if sl != nil {
+			songs = sl.Songs
+		}
+	}
+
+	writeJSON(rctx, action, diffSongs(older.Songs, songs))
+}
+
+// restoreVersion handles requests to set a setlist's songs back to those of the version
+// with the provided ID. The setlist is recreated if it has since been deleted, and the
+// songs it had before are kept as a version so the restore can be undone.
+func (s *server) restoreVersion(rctx *fasthttp.RequestCtx) {
+	action := "restore version"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+	id := string(args.Peek("id"))
+
+	if id == "" {
+		rctx.Error(`{"error":"a version id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	v, err := findVersion(ctx, s.db, setlistName(name), id)
+	if errors.Is(err, errVersionNotFound) {
+		rctx.Error(`{"error":"version not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("%s - getting version: %s", action, err)
+		rctx.Error(`{"error":"failed to get version"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := restore(ctx, s.db, v); err != nil {
+		log.Printf("%s - restoring version: %s", action, err)
+		rctx.Error(`{"error":"failed to restore version"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, name)
+}
+
+// undoSetlist handles requests to revert the last change made to a setlist. Undoing again
+// reverts the change before that, going back as far as versions are kept. If no name is
+// provided the temporary setlist's last change is reverted. Undoing a rename responds with
+// the setlist under its old name.
+func (s *server) undoSetlist(rctx *fasthttp.RequestCtx) {
+	action := "undo setlist"
+	name := rctx.QueryArgs().Peek("name")
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	v, err := undo(ctx, s.db, setlistName(name))
+	if errors.Is(err, errSetlistExists) {
+		rctx.Error(`{"error":"a setlist with that name was created since it was deleted, restore it from the trash under another name"}`, http.StatusConflict)
+		return
+	}
+	if errors.Is(err, errRenamedOver) {
+		writeError(rctx, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("%s - undoing change: %s", action, err)
+		rctx.Error(`{"error":"failed to undo change"}`, http.StatusInternalServerError)
+		return
+	}
+	if v == nil {
+		rctx.Error(`{"error":"no changes to undo"}`, http.StatusConflict)
 		return
 	}
+	if v.OldName != "" {
+		name = []byte(v.OldName)
+	}
+
+	s.changed(ctx, rctx, action, name)
 }
 
 // createSetlist handles requests to create a persisted setlist. A name must be provided
@@ -78,12 +467,119 @@ func (s *server) getSetlist(rctx *fasthttp.RequestCtx) {
 func (s *server) createSetlist(ctx *fasthttp.RequestCtx) {}
 
 // deleteSetlist handles requests to remove a setlist. The name provided must be an exact
-// match in order for the delete to be processed successfully.
-func (s *server) deleteSetlist(ctx *fasthttp.RequestCtx) {}
+// match in order for the delete to be processed successfully. Deleted setlists are moved
+// to the trash, where they can be restored from until the server's retention window ends.
+func (s *server) deleteSetlist(rctx *fasthttp.RequestCtx) {
+	action := "delete setlist"
+	name := rctx.QueryArgs().Peek("name")
+
+	if len(name) == 0 || string(name) == tempSetlistName {
+		rctx.Error(`{"error":"a setlist name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	sl, err := s.db.find(ctx, string(name))
+	if err != nil {
+		log.Printf("%s - getting setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if sl == nil {
+		rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+		return
+	}
+
+	expires := time.Now().UTC().Add(s.trashRetention)
+	if err := delete(ctx, s.db, name, expires); err != nil {
+		log.Printf("%s - deleting setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to delete setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, struct {
+		Name      string    `json:"name"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{sl.Name, expires})
+}
+
+// listTrash handles requests to list the setlists in the trash, most recently deleted
+// first. Setlists that have expired aren't listed since they can no longer be restored.
+func (s *server) listTrash(rctx *fasthttp.RequestCtx) {
+	action := "list trash"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	trashed, err := s.db.trashed(ctx)
+	if err != nil {
+		log.Printf("%s - getting trash: %s", action, err)
+		rctx.Error(`{"error":"failed to get trash"}`, http.StatusInternalServerError)
+		return
+	}
+	if trashed == nil {
+		trashed = []*TrashedSetlist{}
+	}
+
+	writeJSON(rctx, action, trashed)
+}
+
+// restoreTrash handles requests to restore the setlist with the provided trash ID. A name
+// can be provided to restore it under, which is needed when another setlist has taken its
+// name since it was deleted.
+func (s *server) restoreTrash(rctx *fasthttp.RequestCtx) {
+	action := "restore trash"
+	args := rctx.QueryArgs()
+	id, name := string(args.Peek("id")), string(args.Peek("name"))
+
+	if id == "" {
+		rctx.Error(`{"error":"a trash id is required"}`, http.StatusBadRequest)
+		return
+	}
+	if name == tempSetlistName {
+		rctx.Error(`{"error":"setlists can't be restored as the temporary setlist"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	sl, err := restoreTrashed(ctx, s.db, id, name)
+	switch {
+	case errors.Is(err, errTrashedNotFound):
+		rctx.Error(`{"error":"setlist not found in trash"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, errSetlistExists):
+		rctx.Error(`{"error":"a setlist with that name already exists, restore it under another name"}`, http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("%s - restoring setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to restore setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, []byte(sl.Name))
+}
 
 // clearSetlist handles requests to clear all songs from a particular setlist. If no name
 // is provided, then the current temporary setlist will be cleared.
-func (s *server) clearSetlist(ctx *fasthttp.RequestCtx) {}
+func (s *server) clearSetlist(rctx *fasthttp.RequestCtx) {
+	action := "clear setlist"
+	name := rctx.QueryArgs().Peek("name")
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	if err := clear(ctx, s.db, name); err != nil {
+		log.Printf("%s - clearing setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to clear setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, name)
+}
 
 // saveSetlist handles requests to save the current temporary setlist as a persisted
 // setlist with the provided name. A name is required for this request to be processed
@@ -97,30 +593,1813 @@ func (s *server) saveSetlist(ctx *fasthttp.RequestCtx) {}
 func (s *server) updateSetlist(ctx *fasthttp.RequestCtx) {}
 
 // addSong handles requests to append a song to a setlist. If no setlist name is provided
-// the song will be added to the temporary setlist.
-func (s *server) addSong(ctx *fasthttp.RequestCtx) {}
+// the song will be added to the temporary setlist. Requests that are refused, e.g. for
+// going over the setlist's target length, respond with the reason for the refusal. When the
+// streamer reviews requests, songs requested for the temporary setlist are held for review
+// and the pending request is returned with a 202 instead, unless the request carries the
+// mod token. The user parameter names the viewer requesting the song. Setting override
+// lets the streamer or their mods add a song that's still on cooldown, and is refused
+// without the mod token.
+func (s *server) addSong(rctx *fasthttp.RequestCtx) {
+	action := "add song"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+	mod := s.mod(rctx)
+	override := args.GetBool("override")
+	if override && !mod {
+		rctx.Error(`{"error":"only mods can override the cooldown"}`, http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	_, pending, warnings, err := requestSong(ctx, s.db, &songRequest{
+		Setlist:  string(name),
+		Artist:   string(args.Peek("artist")),
+		Song:     string(args.Peek("song")),
+		Gap:      s.songGap,
+		User:     string(args.Peek("user")),
+		Trusted:  mod,
+		Override: override,
+	})
+	if err != nil {
+		var refusal *refusalError
+		switch {
+		case errors.Is(err, errMissingSong):
+			rctx.Error(`{"error":"artist and song are required"}`, http.StatusBadRequest)
+		case errors.As(err, &refusal):
+			writeError(rctx, http.StatusForbidden, refusal.reason)
+		default:
+			log.Printf("%s - adding song: %s", action, err)
+			rctx.Error(`{"error":"failed to add song"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	if pending != nil {
+		rctx.SetStatusCode(http.StatusAccepted)
+		writeJSON(rctx, action, struct {
+			Pending *PendingRequest `json:"pending"`
+		}{pending})
+		return
+	}
+
+	s.changed(ctx, rctx, action, name, warnings...)
+}
 
 // removeSong handles requests to remove a song from a setlist. If no setlist name is
 // provided the song will be removed from the temporary setlist if it exists on the
+// setlist. The song can be identified either by its name or by its position on the
 // setlist.
-func (s *server) removeSong(ctx *fasthttp.RequestCtx) {}
+func (s *server) removeSong(rctx *fasthttp.RequestCtx) {
+	action := "remove song"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+	// a missing or malformed position is treated the same as not providing one
+	position, _ := args.GetUint("position")
 
-// findSong handles requests to look up a song. It looks up a particular song in a user's
-// stored song list and if not found, searches chorus to see if its chart is available to
-// download. It returns an applicable message based on its findings.
-// It currently is not in use and its implementation depends on a couple of factors:
-//   - Whether or not chorus allows external services to search its catalog
-//   - Whether or not a feature allowing users to upload their list of songs is
-//     implemented.
-// func findSong(ctx *fasthttp.RequestCtx) {}
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
 
-// findSongs handles requests to look up potenally multiple songs. It returns a list of
-// matched songs by partial name or artist name. Other parameters TBD. Also TBD is
-// pagination support in case there are more results provided than can be reasonably
-// returned.
-// It is also currently not in use and also depends on the above factors in the comment
-// for findSong
-// func findSongs(ctx *fasthttp.RequestCtx) {}
+	if err := removeSong(ctx, s.db, name, args.Peek("song"), position); err != nil {
+		if errors.Is(err, errMissingSong) {
+			rctx.Error(`{"error":"song or position is required"}`, http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("%s - removing song: %s", action, err)
+		rctx.Error(`{"error":"failed to remove song"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, name)
+}
+
+// targetSetlist handles requests to set the length a setlist is meant to fit in, provided
+// in minutes, and the policy used when adding a song would go past it: warn (default) or
+// refuse. A length of zero removes the setlist's target. If no name is provided, the
+// temporary setlist's target will be set.
+func (s *server) targetSetlist(rctx *fasthttp.RequestCtx) {
+	action := "target setlist"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+
+	minutes, err := args.GetUint("minutes")
+	if err != nil {
+		rctx.Error(`{"error":"minutes must be provided as a whole number"}`, http.StatusBadRequest)
+		return
+	}
+
+	policy := targetPolicy(args.Peek("policy"))
+	switch policy {
+	case "":
+		policy = targetPolicyWarn
+	case targetPolicyWarn, targetPolicyRefuse:
+	default:
+		rctx.Error(`{"error":"policy must be one of warn or refuse"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.db.target(ctx, setlistName(name), minutes*60, policy); err != nil {
+		log.Printf("%s - setting target: %s", action, err)
+		rctx.Error(`{"error":"failed to set setlist target"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.changed(ctx, rctx, action, name)
+}
+
+// nextSong handles requests to move on from the song being played on the temporary
+// setlist, marking it as played. Songs played while a session is started are recorded in
+// the streamer's history.
+func (s *server) nextSong(rctx *fasthttp.RequestCtx) {
+	action := "next song"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	played, err := playNext(ctx, s.db, time.Now())
+	if err != nil {
+		log.Printf("%s - playing next song: %s", action, err)
+		rctx.Error(`{"error":"failed to move on to the next song"}`, http.StatusInternalServerError)
+		return
+	}
+	if played == nil {
+		rctx.Error(`{"error":"nothing is playing"}`, http.StatusNotFound)
+		return
+	}
+
+	s.changed(ctx, rctx, action, nil)
+}
+
+// changed handles the tail end of requests that modify a setlist. It looks up the
+// setlist's current state, passes it along to the server's notifiers and writes it as
+// the response along with any warnings about the change. Notifier failures are logged
+// but do not fail the request since the change itself has already been made.
+func (s *server) changed(ctx context.Context, rctx *fasthttp.RequestCtx, action string, name []byte, warnings ...string) {
+	sl, err := s.refresh(ctx, action, name)
+	if err != nil {
+		log.Printf("%s - getting updated setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, struct {
+		*Setlist
+		Warnings []string `json:"warnings,omitempty"`
+	}{sl, warnings})
+}
+
+// generateSetlist handles requests to build a persisted setlist from songs in the
+// streamer's library. Songs can be filtered the same way as when listing the library and
+// songs played in the last not_played streams can be left out. Blocked songs are never
+// picked. The setlist fits in the provided number of minutes when set, otherwise it is
+// made up of count songs. Songs are picked at random using the provided seed, or a random
+// one, which is included in the response so the setlist can be generated again. If the
+// setlist exists its songs are replaced.
+func (s *server) generateSetlist(rctx *fasthttp.RequestCtx) {
+	action := "generate setlist"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+
+	if len(name) == 0 || string(name) == tempSetlistName {
+		rctx.Error(`{"error":"a setlist name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	f, err := parseSongFilter(args)
+	if err != nil {
+		writeError(rctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	r := &generateRequest{Filter: f, Gap: s.songGap, Seed: rand.Uint64()}
+	for _, n := range []struct {
+		arg string
+		dst *int
+	}{
+		{"not_played", &r.NotPlayedIn},
+		{"minutes", &r.Target},
+		{"count", &r.Count},
+	} {
+		if !args.Has(n.arg) {
+			continue
+		}
+		if *n.dst, err = args.GetUint(n.arg); err != nil {
+			writeError(rctx, http.StatusBadRequest, n.arg+" must be a whole number")
+			return
+		}
+	}
+	r.Target *= 60
+	if args.Has("seed") {
+		if r.Seed, err = strconv.ParseUint(string(args.Peek("seed")), 10, 64); err != nil {
+			rctx.Error(`{"error":"seed must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 10*time.Second)
+	defer cancel()
+
+	library, err := s.db.library(ctx)
+	if err != nil {
+		log.Printf("%s - getting library: %s", action, err)
+		rctx.Error(`{"error":"failed to get library"}`, http.StatusInternalServerError)
+		return
+	}
+
+	b, err := loadBlocklist(ctx, s.db)
+	if err != nil {
+		log.Printf("%s - getting blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+	library = b.unblocked(library)
+
+	var played []*Play
+	if r.NotPlayedIn > 0 {
+		if played, err = s.db.history(ctx, historyQuery{Streams: r.NotPlayedIn}); err != nil {
+			log.Printf("%s - getting history: %s", action, err)
+			rctx.Error(`{"error":"failed to get play history"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := replace(ctx, s.db, string(name), generate(library, played, r)); err != nil {
+		log.Printf("%s - replacing setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to save generated setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := s.db.target(ctx, string(name), r.Target, targetPolicyWarn); err != nil {
+		log.Printf("%s - setting target: %s", action, err)
+		rctx.Error(`{"error":"failed to set setlist target"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sl, err := setlist(ctx, s.db, name)
+	if err != nil {
+		log.Printf("%s - getting setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if sl != nil {
+		sl.summarize(s.songGap)
+	}
+
+	writeJSON(rctx, action, struct {
+		*Setlist
+		Seed uint64 `json:"seed,string"`
+	}{sl, r.Seed})
+}
+
+// exportSetlist handles requests to download a setlist in one of the supported export
+// formats: csv, markdown, m3u or text. If no name is provided, the temporary setlist will
+// be exported.
+func (s *server) exportSetlist(rctx *fasthttp.RequestCtx) {
+	action := "export setlist"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+
+	e, ok := exporters[string(args.Peek("format"))]
+	if !ok {
+		rctx.Error(`{"error":"format must be one of csv, markdown, m3u or text"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	sl, err := setlist(ctx, s.db, name)
+	if err != nil {
+		log.Printf("%s - getting setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if sl == nil {
+		rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+		return
+	}
+
+	rctx.SetContentType(e.contentType)
+	rctx.Response.Header.Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", sl.Name+"."+e.extension),
+	)
+	if err := e.write(rctx, sl); err != nil {
+		log.Printf("%s - writing export: %s", action, err)
+		rctx.Error(`{"error":"failed to export setlist"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// exportGameSetlist handles requests to download a setlist as a rhythm game setlist file
+// that can be loaded straight into the game: clonehero or yarg. Songs are matched to
+// charts in the streamer's library. If any song has no matching chart, or its chart lacks
+// the hash the game identifies charts by, those songs are returned instead unless partial
+// is set, in which case they're left out of the file and their counts are reported in the
+// X-Unmatched-Songs and X-Skipped-Songs headers. Nothing is exported if no song can be.
+func (s *server) exportGameSetlist(rctx *fasthttp.RequestCtx) {
+	action := "export game setlist"
+	args := rctx.QueryArgs()
+	name := args.Peek("name")
+
+	g, ok := gameExporters[string(args.Peek("game"))]
+	if !ok {
+		rctx.Error(`{"error":"game must be one of clonehero or yarg"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	sl, err := setlist(ctx, s.db, name)
+	if err != nil {
+		log.Printf("%s - getting setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if sl == nil {
+		rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+		return
+	}
+
+	library, err := s.db.library(ctx)
+	if err != nil {
+		log.Printf("%s - getting library: %s", action, err)
+		rctx.Error(`{"error":"failed to get library"}`, http.StatusInternalServerError)
+		return
+	}
+
+	charts, unmatched, skipped := g.gameCharts(sl, library)
+	partial := args.GetBool("partial") && (len(charts) > 0 || len(sl.Songs) == 0)
+	if len(unmatched)+len(skipped) > 0 && !partial {
+		rctx.SetStatusCode(http.StatusUnprocessableEntity)
+		writeJSON(rctx, action, struct {
+			Error     string  `json:"error"`
+			Unmatched []*Song `json:"unmatched,omitempty"`
+			Skipped   []*Song `json:"skipped,omitempty"`
+		}{
+			Error:     "some songs have no matching chart in the library or no hash for the game",
+			Unmatched: unmatched,
+			Skipped:   skipped,
+		})
+		return
+	}
+
+	rctx.SetContentType(g.contentType)
+	rctx.Response.Header.Set("X-Unmatched-Songs", strconv.Itoa(len(unmatched)))
+	rctx.Response.Header.Set("X-Skipped-Songs", strconv.Itoa(len(skipped)))
+	rctx.Response.Header.Set(
+		"Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", sl.Name+"."+g.extension),
+	)
+	if err := g.write(rctx, sl, charts); err != nil {
+		log.Printf("%s - writing export: %s", action, err)
+		rctx.Error(`{"error":"failed to export setlist"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// importSetlist handles requests to load a persisted setlist from "Artist - Song" lines,
+// CSV or the JSON setlist shape. The data can be sent as the request body or as a file
+// uploaded under the "file" form field. If the setlist exists its songs are replaced,
+// otherwise it is created. The response reports which lines were parsed, skipped or
+// flagged as duplicates.
+func (s *server) importSetlist(rctx *fasthttp.RequestCtx) {
+	action := "import setlist"
+	args := rctx.QueryArgs()
+
+	data := rctx.PostBody()
+	if fh, err := rctx.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			log.Printf("%s - opening uploaded file: %s", action, err)
+			rctx.Error(`{"error":"failed to read uploaded file"}`, http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+
+		if data, err = io.ReadAll(f); err != nil {
+			log.Printf("%s - reading uploaded file: %s", action, err)
+			rctx.Error(`{"error":"failed to read uploaded file"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := parseImport(string(args.Peek("format")), data)
+	if err != nil {
+		if errors.Is(err, errUnknownImportFormat) {
+			rctx.Error(`{"error":"format must be one of csv, json or text"}`, http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("%s - parsing import: %s", action, err)
+		rctx.Error(`{"error":"failed to parse import"}`, http.StatusBadRequest)
+		return
+	}
+
+	// a name provided with the request takes precedence over one included in the data
+	if name := args.Peek("name"); len(name) > 0 {
+		report.Setlist = string(name)
+	}
+	if report.Setlist == "" || report.Setlist == tempSetlistName {
+		rctx.Error(`{"error":"a setlist name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	if err := replace(ctx, s.db, report.Setlist, report.songs()); err != nil {
+		log.Printf("%s - replacing setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to import setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, report)
+}
+
+// spinWheel handles requests to pick a random song, returning an event describing the
+// spin for an overlay to animate. Songs are picked from the streamer's library, or from
+// the setlist named by the setlist parameter, and can be filtered the same way as when
+// listing the library, e.g. with max_difficulty. Blocked songs, songs played on the
+// open session and songs already on the temporary setlist are never picked and songs
+// played often on recent streams are less likely to be. If add is set, the picked song is
+// added to the temporary setlist.
+func (s *server) spinWheel(rctx *fasthttp.RequestCtx) {
+	action := "spin wheel"
+	args := rctx.QueryArgs()
+
+	f, err := parseSongFilter(args)
+	if err != nil {
+		writeError(rctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	r := &wheelRequest{Filter: f, Exclude: map[string]bool{}, Seed: rand.Uint64()}
+	if args.Has("segments") {
+		if r.Segments, err = args.GetUint("segments"); err != nil {
+			rctx.Error(`{"error":"segments must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if args.Has("seed") {
+		if r.Seed, err = strconv.ParseUint(string(args.Peek("seed")), 10, 64); err != nil {
+			rctx.Error(`{"error":"seed must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	var pool []*Song
+	if name := args.Peek("setlist"); len(name) > 0 {
+		sl, err := s.db.find(ctx, string(name))
+		if err != nil {
+			log.Printf("%s - getting setlist: %s", action, err)
+			rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+			return
+		}
+		if sl == nil {
+			rctx.Error(`{"error":"setlist not found"}`, http.StatusNotFound)
+			return
+		}
+		pool = sl.Songs
+	} else {
+		library, err := s.db.library(ctx)
+		if err != nil {
+			log.Printf("%s - getting library: %s", action, err)
+			rctx.Error(`{"error":"failed to get library"}`, http.StatusInternalServerError)
+			return
+		}
+		for _, c := range library {
+			pool = append(pool, &c.Song)
+		}
+	}
+
+	b, err := loadBlocklist(ctx, s.db)
+	if err != nil {
+		log.Printf("%s - getting blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+	for _, song := range pool {
+		if b.check(song) != "" {
+			r.Exclude[songKey(song)] = true
+		}
+	}
+
+	temp, err := setlist(ctx, s.db, nil)
+	if err != nil {
+		log.Printf("%s - getting temp setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if temp != nil {
+		for _, song := range temp.Songs {
+			r.Exclude[songKey(song)] = true
+		}
+	}
+
+	open, err := s.db.session(ctx, "")
+	if err != nil {
+		log.Printf("%s - getting open session: %s", action, err)
+		rctx.Error(`{"error":"failed to get session"}`, http.StatusInternalServerError)
+		return
+	}
+	// without an open session nothing has been played on the current stream yet
+	if open != nil {
+		current, err := s.db.history(ctx, historyQuery{Stream: open.ID})
+		if err != nil {
+			log.Printf("%s - getting history: %s", action, err)
+			rctx.Error(`{"error":"failed to get play history"}`, http.StatusInternalServerError)
+			return
+		}
+		for k := range playedSongs(current) {
+			r.Exclude[k] = true
+		}
+	}
+
+	recent, err := s.db.history(ctx, historyQuery{Streams: wheelHistoryStreams})
+	if err != nil {
+		log.Printf("%s - getting history: %s", action, err)
+		rctx.Error(`{"error":"failed to get play history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	e := spin(pool, recent, r)
+	if e == nil {
+		rctx.Error(`{"error":"no songs can be picked"}`, http.StatusNotFound)
+		return
+	}
+
+	if args.GetBool("add") {
+		_, _, err := addSong(ctx, s.db, &songRequest{Artist: e.Song.Artist, Song: e.Song.Name, Gap: s.songGap})
+		var refusal *refusalError
+		switch {
+		case errors.As(err, &refusal):
+			e.Refused = refusal.reason
+		case err != nil:
+			log.Printf("%s - adding song: %s", action, err)
+			rctx.Error(`{"error":"failed to add song"}`, http.StatusInternalServerError)
+			return
+		default:
+			e.Added = true
+			if sl, err := setlist(ctx, s.db, nil); err != nil {
+				log.Printf("%s - getting updated setlist: %s", action, err)
+			} else if sl != nil {
+				sl.summarize(s.songGap)
+				s.notify(ctx, action, sl)
+			}
+		}
+	}
+
+	writeJSON(rctx, action, e)
+}
+
+// getPoll handles requests for the state of the streamer's most recent poll, including
+// the current tally while it's open and the winner once it has closed.
+func (s *server) getPoll(rctx *fasthttp.RequestCtx) {
+	action := "get poll"
+
+	p := s.polls.current(streamerFrom(rctx))
+	if p == nil {
+		rctx.Error(`{"error":"no poll found"}`, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(rctx, action, p)
+}
+
+// openPoll handles requests to open a poll on the next song. Candidates are picked from
+// the temporary setlist or, if from is random, from the unblocked songs in the streamer's
+// library. size sets the number of candidates and seconds how long the poll stays open.
+// Once it closes, the winner is moved to the top of the queue, right after the song being
+// played.
+func (s *server) openPoll(rctx *fasthttp.RequestCtx) {
+	action := "open poll"
+	args := rctx.QueryArgs()
+
+	source := pollSource(args.Peek("from"))
+	switch source {
+	case "":
+		source = pollSourceQueue
+	case pollSourceQueue, pollSourceRandom:
+	default:
+		rctx.Error(`{"error":"from must be queue or random"}`, http.StatusBadRequest)
+		return
+	}
+
+	size := defaultPollSize
+	if args.Has("size") {
+		var err error
+		if size, err = args.GetUint("size"); err != nil || size < 2 || size > maxPollSize {
+			writeError(rctx, http.StatusBadRequest, fmt.Sprintf("size must be a number from 2 to %d", maxPollSize))
+			return
+		}
+	}
+
+	seconds := defaultPollSeconds
+	if args.Has("seconds") {
+		var err error
+		if seconds, err = args.GetUint("seconds"); err != nil || seconds == 0 {
+			rctx.Error(`{"error":"seconds must be a positive whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	temp, err := setlist(ctx, s.db, nil)
+	if err != nil {
+		log.Printf("%s - getting temp setlist: %s", action, err)
+		rctx.Error(`{"error":"failed to get setlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var library []*Chart
+	if source == pollSourceRandom {
+		if library, err = s.db.library(ctx); err != nil {
+			log.Printf("%s - getting library: %s", action, err)
+			rctx.Error(`{"error":"failed to get library"}`, http.StatusInternalServerError)
+			return
+		}
+		b, err := loadBlocklist(ctx, s.db)
+		if err != nil {
+			log.Printf("%s - getting blocklist: %s", action, err)
+			rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+			return
+		}
+		library = b.unblocked(library)
+	}
+
+	candidates := pollCandidates(source, temp, library, size, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	if len(candidates) < 2 {
+		rctx.Error(`{"error":"not enough songs to vote on"}`, http.StatusNotFound)
+		return
+	}
+
+	streamer := streamerFrom(rctx)
+	p, err := s.polls.open(streamer, source, candidates, time.Duration(seconds)*time.Second, func() {
+		if _, err := s.closePoll(streamer); err != nil {
+			log.Printf("close poll - %s", err)
+		}
+	})
+	if errors.Is(err, errPollOpen) {
+		writeError(rctx, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("%s - opening poll: %s", action, err)
+		rctx.Error(`{"error":"failed to open poll"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, p)
+}
+
+// votePoll handles requests to vote in the streamer's open poll. The user parameter
+// identifies the viewer, who gets a single vote, and choice is the candidate's number.
+func (s *server) votePoll(rctx *fasthttp.RequestCtx) {
+	action := "vote poll"
+	args := rctx.QueryArgs()
+
+	user := string(args.Peek("user"))
+	if user == "" {
+		rctx.Error(`{"error":"user is required"}`, http.StatusBadRequest)
+		return
+	}
+	choice, err := args.GetUint("choice")
+	if err != nil {
+		rctx.Error(`{"error":"choice must be a whole number"}`, http.StatusBadRequest)
+		return
+	}
+
+	p, err := s.polls.vote(streamerFrom(rctx), user, choice)
+	if err != nil {
+		var refusal *refusalError
+		if errors.As(err, &refusal) {
+			writeError(rctx, http.StatusForbidden, refusal.reason)
+			return
+		}
+		log.Printf("%s - voting: %s", action, err)
+		rctx.Error(`{"error":"failed to vote"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, p)
+}
+
+// closePollEarly handles requests to close the streamer's open poll before its time is
+// up, moving the winner to the top of the queue.
+func (s *server) closePollEarly(rctx *fasthttp.RequestCtx) {
+	action := "close poll"
+
+	p, err := s.closePoll(streamerFrom(rctx))
+	if p == nil {
+		rctx.Error(`{"error":"there is no poll open"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		var refusal *refusalError
+		if errors.As(err, &refusal) {
+			writeError(rctx, http.StatusForbidden, refusal.reason)
+			return
+		}
+		log.Printf("%s - %s", action, err)
+		rctx.Error(`{"error":"failed to move winning song"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, p)
+}
+
+// closePoll closes the streamer's open poll and moves the winner to the top of their
+// temporary setlist. It runs both when a poll's time is up and when it's closed early, so
+// rather than using a request's context it scopes a new one to the streamer. The closed
+// poll is nil if there was no poll open.
+func (s *server) closePoll(streamer string) (*poll, error) {
+	action := "close poll"
+
+	p := s.polls.close(streamer)
+	if p == nil || p.Winner == nil {
+		return p, nil
+	}
+
+	ctx, cancel := context.WithTimeout(withChange(withStreamer(context.Background(), streamer), ""), 5*time.Second)
+	defer cancel()
+
+	if err := promote(ctx, s.db, p.Winner, s.songGap); err != nil {
+		return p, fmt.Errorf("moving winning song: %w", err)
+	}
+
+	if sl, err := setlist(ctx, s.db, nil); err != nil {
+		log.Printf("%s - getting updated setlist: %s", action, err)
+	} else if sl != nil {
+		sl.summarize(s.songGap)
+		s.notify(ctx, action, sl)
+	}
+
+	return p, nil
+}
+
+// tipWebhook handles tip events sent by external services, requesting the event's song on
+// the streamer's temporary setlist in the priority tier its amount earns. Events must be
+// signed with the tip secret, recent and carry an ID, and are refused when the webhook
+// isn't configured with a secret. Events with an ID that was already handled are
+// acknowledged without being handled again, so a captured event can't be replayed.
+func (s *server) tipWebhook(rctx *fasthttp.RequestCtx) {
+	action := "tip webhook"
+
+	if len(s.tipSecret) == 0 {
+		rctx.Error(`{"error":"tips aren't enabled"}`, http.StatusNotFound)
+		return
+	}
+
+	h := &rctx.Request.Header
+	id := string(h.Peek(tipIDHeader))
+	timestamp := string(h.Peek(tipTimestampHeader))
+	body := rctx.PostBody()
+
+	if !verifyTip(s.tipSecret, id, timestamp, body, string(h.Peek(tipSignatureHeader))) {
+		rctx.Error(`{"error":"invalid signature"}`, http.StatusUnauthorized)
+		return
+	}
+	if id == "" {
+		rctx.Error(`{"error":"an event ID is required"}`, http.StatusBadRequest)
+		return
+	}
+	sent, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil || time.Since(sent) > tipMaxAge {
+		rctx.Error(`{"error":"event is too old"}`, http.StatusForbidden)
+		return
+	}
+
+	var e tipEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		rctx.Error(`{"error":"invalid event"}`, http.StatusBadRequest)
+		return
+	}
+	if !s.tipSeen.first(id) {
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(withStreamer(rctx, e.Streamer), 5*time.Second)
+	defer cancel()
+
+	_, pending, warnings, err := requestSong(ctx, s.db, &songRequest{
+		Artist:   e.Artist,
+		Song:     e.Song,
+		Gap:      s.songGap,
+		Priority: s.tipTiers.tier(e.Kind, e.Amount),
+		User:     e.User,
+	})
+	if err != nil {
+		var refusal *refusalError
+		switch {
+		case errors.Is(err, errMissingSong):
+			rctx.Error(`{"error":"artist and song are required"}`, http.StatusBadRequest)
+		case errors.As(err, &refusal):
+			writeError(rctx, http.StatusForbidden, refusal.reason)
+		default:
+			// let the service retry the event
+			s.tipSeen.forget(id)
+			log.Printf("%s - adding song: %s", action, err)
+			rctx.Error(`{"error":"failed to add song"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	if pending != nil {
+		rctx.SetStatusCode(http.StatusAccepted)
+		writeJSON(rctx, action, struct {
+			Pending *PendingRequest `json:"pending"`
+		}{pending})
+		return
+	}
+
+	s.changed(ctx, rctx, action, nil, warnings...)
+}
+
+// twitchEventSub handles Twitch EventSub webhook requests. Callback verifications are
+// answered with their challenge and redemptions of the song request reward are requested
+// on the broadcaster's temporary setlist, in the priority tier the reward's cost earns as
+// a points tip. Messages must be signed with the Twitch secret and recent. Retried
+// deliveries of messages that were already handled are acknowledged without being
+// handled again.
+func (s *server) twitchEventSub(rctx *fasthttp.RequestCtx) {
+	action := "twitch eventsub"
+
+	if len(s.twitchSecret) == 0 {
+		rctx.Error(`{"error":"twitch isn't enabled"}`, http.StatusNotFound)
+		return
+	}
+
+	h := &rctx.Request.Header
+	id := string(h.Peek("Twitch-Eventsub-Message-Id"))
+	timestamp := string(h.Peek("Twitch-Eventsub-Message-Timestamp"))
+	body := rctx.PostBody()
+
+	if !verifyEventSub(s.twitchSecret, id, timestamp, body, string(h.Peek("Twitch-Eventsub-Message-Signature"))) {
+		rctx.Error(`{"error":"invalid signature"}`, http.StatusForbidden)
+		return
+	}
+	sent, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil || time.Since(sent) > eventSubMaxAge {
+		rctx.Error(`{"error":"message is too old"}`, http.StatusForbidden)
+		return
+	}
+
+	var m eventSubMessage
+	if err := json.Unmarshal(body, &m); err != nil {
+		rctx.Error(`{"error":"invalid message"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch string(h.Peek("Twitch-Eventsub-Message-Type")) {
+	case eventSubVerification:
+		rctx.SetContentType("text/plain")
+		rctx.SetBodyString(m.Challenge)
+		return
+	case eventSubRevocation:
+		log.Printf("%s - subscription %s revoked: %s", action, m.Subscription.ID, m.Subscription.Status)
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	case eventSubNotification:
+	default:
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	}
+
+	e := m.Event
+	if m.Subscription.Type != eventSubRedemption || e == nil || e.Reward.ID != s.twitchRewardID {
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	}
+	if !s.eventSubSeen.first(id) {
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	}
+
+	artist, song, ok := parseSongInput(e.UserInput)
+	if !ok {
+		log.Printf("%s - ignoring redemption %s: %q isn't an artist and song", action, e.ID, e.UserInput)
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(withStreamer(rctx, e.BroadcasterLogin), 5*time.Second)
+	defer cancel()
+
+	_, pending, warnings, err := requestSong(ctx, s.db, &songRequest{
+		Artist:   artist,
+		Song:     song,
+		Gap:      s.songGap,
+		Priority: s.tipTiers.tier("points", e.Reward.Cost),
+		User:     e.UserLogin,
+	})
+	if err != nil {
+		var refusal *refusalError
+		if errors.As(err, &refusal) {
+			log.Printf("%s - refused redemption %s: %s", action, e.ID, refusal.reason)
+			rctx.SetStatusCode(http.StatusNoContent)
+			return
+		}
+		// let twitch retry the delivery
+		s.eventSubSeen.forget(id)
+		log.Printf("%s - adding song: %s", action, err)
+		rctx.Error(`{"error":"failed to add song"}`, http.StatusInternalServerError)
+		return
+	}
+	if pending != nil {
+		rctx.SetStatusCode(http.StatusNoContent)
+		return
+	}
+
+	s.changed(ctx, rctx, action, nil, warnings...)
+}
+
+// discordInteractions handles Discord's interactions endpoint requests. Pings are answered
+// with a pong and the /sr, /queue and /nowplaying slash commands work with the temporary
+// setlist of the streamer the Discord server is linked to. Requests must be signed with
+// the application's key. Replies other than embeds are only shown to the user who sent
+// the command.
+func (s *server) discordInteractions(rctx *fasthttp.RequestCtx) {
+	action := "discord interaction"
+
+	if s.discordKey == nil {
+		rctx.Error(`{"error":"discord isn't enabled"}`, http.StatusNotFound)
+		return
+	}
+
+	h := &rctx.Request.Header
+	body := rctx.PostBody()
+	if !verifyDiscord(s.discordKey, string(h.Peek("X-Signature-Ed25519")), string(h.Peek("X-Signature-Timestamp")), body) {
+		rctx.Error(`{"error":"invalid request signature"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var i discordInteraction
+	if err := json.Unmarshal(body, &i); err != nil {
+		rctx.Error(`{"error":"invalid interaction"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch i.Type {
+	case discordPing:
+		writeJSON(rctx, action, &discordResponse{Type: discordPong})
+		return
+	case discordApplicationCommand:
+	default:
+		rctx.Error(`{"error":"unsupported interaction"}`, http.StatusBadRequest)
+		return
+	}
+
+	streamer, ok := s.discordGuilds[i.GuildID]
+	if !ok {
+		writeJSON(rctx, action, discordMessage("This server isn't linked to a streamer."))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(withStreamer(rctx, streamer), 5*time.Second)
+	defer cancel()
+
+	var (
+		resp *discordResponse
+		err  error
+	)
+	switch i.Data.Name {
+	case "sr":
+		resp, err = s.discordRequestSong(ctx, action, &i)
+	case "queue", "nowplaying":
+		var sl *Setlist
+		if sl, err = s.db.find(ctx, tempSetlistName); err == nil {
+			embed := nowPlayingEmbed(sl)
+			if i.Data.Name == "queue" {
+				if sl != nil {
+					sl.summarize(s.songGap)
+				}
+				embed = queueEmbed(sl)
+			}
+			resp = &discordResponse{Type: discordChannelMessageWithSource, Data: &discordResponseData{Embeds: []*discordEmbed{embed}}}
+		}
+	default:
+		resp = discordMessage("Unknown command.")
+	}
+	if err != nil {
+		// discord only shows responses, so failures are still answered
+		log.Printf("%s - /%s: %s", action, i.Data.Name, err)
+		resp = discordMessage("Something went wrong, try again later.")
+	}
+
+	writeJSON(rctx, action, resp)
+}
+
+// discordRequestSong handles the /sr command, requesting a song on the temporary setlist
+// and responding with the song added, as found in the library, and the updated queue.
+func (s *server) discordRequestSong(ctx context.Context, action string, i *discordInteraction) (*discordResponse, error) {
+	added, pending, warnings, err := requestSong(ctx, s.db, &songRequest{
+		Artist: i.option("artist"),
+		Song:   i.option("song"),
+		Gap:    s.songGap,
+		User:   i.username(),
+	})
+	var refusal *refusalError
+	switch {
+	case errors.Is(err, errMissingSong):
+		return discordMessage("An artist and song are required."), nil
+	case errors.As(err, &refusal):
+		return discordMessage(refusal.reason), nil
+	case err != nil:
+		return nil, err
+	case pending != nil:
+		return discordMessage(fmt.Sprintf("Your request for **%s** by %s is waiting for approval.", pending.Song.Name, pending.Song.Artist)), nil
+	}
+
+	sl, err := s.refresh(ctx, action, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("%s requested **%s** by %s", i.username(), added.Name, added.Artist)
+	for _, w := range warnings {
+		content += "\n" + w
+	}
+
+	return &discordResponse{
+		Type: discordChannelMessageWithSource,
+		Data: &discordResponseData{Content: content, Embeds: []*discordEmbed{queueEmbed(sl)}},
+	}, nil
+}
+
+// getReview handles requests for the streamer's review queue: whether requests are being
+// reviewed and the requests waiting for review, oldest first.
+func (s *server) getReview(rctx *fasthttp.RequestCtx) {
+	action := "get review"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	reviewing, err := s.db.reviewing(ctx)
+	if err != nil {
+		log.Printf("%s - looking up whether requests are reviewed: %s", action, err)
+		rctx.Error(`{"error":"failed to get review queue"}`, http.StatusInternalServerError)
+		return
+	}
+	pending, err := s.db.pending(ctx)
+	if err != nil {
+		log.Printf("%s - getting pending requests: %s", action, err)
+		rctx.Error(`{"error":"failed to get review queue"}`, http.StatusInternalServerError)
+		return
+	}
+	if pending == nil {
+		pending = []*PendingRequest{}
+	}
+
+	writeJSON(rctx, action, struct {
+		Reviewing bool              `json:"reviewing"`
+		Pending   []*PendingRequest `json:"pending"`
+	}{reviewing, pending})
+}
+
+// enableReview handles requests to turn reviewing viewers' requests on or off with the on
+// parameter. Requests already waiting for review stay pending when it's turned off.
+func (s *server) enableReview(rctx *fasthttp.RequestCtx) {
+	action := "enable review"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	on := rctx.QueryArgs().GetBool("on")
+	if err := s.db.review(ctx, on); err != nil {
+		log.Printf("%s - setting review: %s", action, err)
+		rctx.Error(`{"error":"failed to set review"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, struct {
+		Reviewing bool `json:"reviewing"`
+	}{on})
+}
+
+// approveRequest handles requests to approve the pending request with the provided id,
+// adding its song to the temporary setlist for the viewer who requested it.
+func (s *server) approveRequest(rctx *fasthttp.RequestCtx) {
+	action := "approve request"
+
+	id := string(rctx.QueryArgs().Peek("id"))
+	if id == "" {
+		rctx.Error(`{"error":"an id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	p, warnings, err := approve(ctx, s.db, id, s.songGap)
+	if err != nil {
+		var refusal *refusalError
+		if errors.As(err, &refusal) {
+			writeError(rctx, http.StatusForbidden, refusal.reason)
+			return
+		}
+		log.Printf("%s - approving request: %s", action, err)
+		rctx.Error(`{"error":"failed to approve request"}`, http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		rctx.Error(`{"error":"request not found"}`, http.StatusNotFound)
+		return
+	}
+
+	s.changed(ctx, rctx, action, nil, warnings...)
+}
+
+// rejectRequest handles requests to reject the pending request with the provided id. An
+// optional reason is included in the message relayed to chat, which is also returned for
+// bots to relay themselves.
+func (s *server) rejectRequest(rctx *fasthttp.RequestCtx) {
+	action := "reject request"
+	args := rctx.QueryArgs()
+
+	id := string(args.Peek("id"))
+	if id == "" {
+		rctx.Error(`{"error":"an id is required"}`, http.StatusBadRequest)
+		return
+	}
+	reason := string(args.Peek("reason"))
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	p, err := s.db.resolve(ctx, id)
+	if err != nil {
+		log.Printf("%s - resolving request: %s", action, err)
+		rctx.Error(`{"error":"failed to reject request"}`, http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		rctx.Error(`{"error":"request not found"}`, http.StatusNotFound)
+		return
+	}
+
+	msg := rejectionMessage(p, reason)
+	for _, a := range s.announcers {
+		if err := a.announce(ctx, msg); err != nil {
+			log.Printf("%s - announcing rejection: %s", action, err)
+		}
+	}
+
+	writeJSON(rctx, action, struct {
+		Rejected *PendingRequest `json:"rejected"`
+		Reason   string          `json:"reason,omitempty"`
+		Message  string          `json:"message"`
+	}{p, reason, msg})
+}
+
+// getStats handles requests for statistics on the streamer's played history: their most
+// played songs and artists, most active requesters, requests per stream, average queue
+// wait and library songs that weren't played. History can be narrowed down with streams,
+// since and until, and limit sets the length of the top lists.
+func (s *server) getStats(rctx *fasthttp.RequestCtx) {
+	action := "get stats"
+	args := rctx.QueryArgs()
+
+	q, err := parseHistoryQuery(args)
+	if err != nil {
+		writeError(rctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := defaultStatsLimit
+	if args.Has("limit") {
+		if limit, err = args.GetUint("limit"); err != nil {
+			rctx.Error(`{"error":"limit must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 10*time.Second)
+	defer cancel()
+
+	st, err := s.db.stats(ctx, q, limit)
+	if err != nil {
+		log.Printf("%s - getting stats: %s", action, err)
+		rctx.Error(`{"error":"failed to get stats"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, st)
+}
+
+// getWrapped handles requests for a recap of the streamer's played history over the year
+// provided, the current year by default, or from since to until. The recap is returned as
+// JSON or, if format is html, as a standalone page that can be shared.
+func (s *server) getWrapped(rctx *fasthttp.RequestCtx) {
+	action := "get wrapped"
+	args := rctx.QueryArgs()
+
+	format := string(args.Peek("format"))
+	if format != "" && format != "json" && format != "html" {
+		rctx.Error(`{"error":"format must be json or html"}`, http.StatusBadRequest)
+		return
+	}
+
+	q, err := parseHistoryQuery(args)
+	if err != nil {
+		writeError(rctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	since, until := q.Since, q.Until
+	if since.IsZero() && until.IsZero() {
+		year := time.Now().UTC().Year()
+		if args.Has("year") {
+			if year, err = args.GetUint("year"); err != nil {
+				rctx.Error(`{"error":"year must be a whole number"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		since = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		until = since.AddDate(1, 0, 0)
+	} else if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	limit := defaultStatsLimit
+	if args.Has("limit") {
+		if limit, err = args.GetUint("limit"); err != nil {
+			rctx.Error(`{"error":"limit must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 10*time.Second)
+	defer cancel()
+
+	// earlier plays are needed to tell which songs were played for the first time
+	history, err := s.db.history(ctx, historyQuery{Until: until})
+	if err != nil {
+		log.Printf("%s - getting history: %s", action, err)
+		rctx.Error(`{"error":"failed to get play history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w := wrap(streamerFrom(rctx), history, since, until, limit)
+	if format != "html" {
+		writeJSON(rctx, action, w)
+		return
+	}
+
+	rctx.SetContentType("text/html; charset=utf-8")
+	if err := w.writeHTML(rctx); err != nil {
+		log.Printf("%s - writing html: %s", action, err)
+		rctx.Error(`{"error":"failed to render wrapped"}`, http.StatusInternalServerError)
+	}
+}
+
+// addScore handles requests to attach a score to a song played on the current stream,
+// identified by artist and song or, if they aren't provided, the last song played. The
+// score, accuracy percentage and stars are provided as numbers, fc marks a full combo and
+// instrument and difficulty default to guitar and expert.
+func (s *server) addScore(rctx *fasthttp.RequestCtx) {
+	action := "add score"
+	args := rctx.QueryArgs()
+
+	sc := &Score{
+		Instrument: string(args.Peek("instrument")),
+		Difficulty: string(args.Peek("difficulty")),
+		FullCombo:  args.GetBool("fc"),
+	}
+	if sc.Instrument == "" {
+		sc.Instrument = defaultScoreInstrument
+	}
+	if sc.Difficulty == "" {
+		sc.Difficulty = defaultScoreDifficulty
+	}
+	artist, song := string(args.Peek("artist")), string(args.Peek("song"))
+	if artist != "" || song != "" {
+		if artist == "" || song == "" {
+			rctx.Error(`{"error":"artist and song must be provided together"}`, http.StatusBadRequest)
+			return
+		}
+		sc.Song = &Song{Artist: artist, Name: song}
+	}
+
+	var err error
+	if sc.Score, err = args.GetUint("score"); err != nil {
+		rctx.Error(`{"error":"score must be a whole number"}`, http.StatusBadRequest)
+		return
+	}
+	if args.Has("accuracy") {
+		if sc.Accuracy, err = args.GetUfloat("accuracy"); err != nil || sc.Accuracy > 100 {
+			rctx.Error(`{"error":"accuracy must be a percentage from 0 to 100"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if args.Has("stars") {
+		if sc.Stars, err = args.GetUint("stars"); err != nil {
+			rctx.Error(`{"error":"stars must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	if err := attachScore(ctx, s.db, sc); err != nil {
+		if errors.Is(err, errNotPlayed) {
+			writeError(rctx, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("%s - %s", action, err)
+		rctx.Error(`{"error":"failed to add score"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, sc)
+}
+
+// personalBest handles requests for the streamer's best scores on a song for each
+// instrument and difficulty. The song is identified by artist and song, or by q in the
+// form "Artist - Song" or just the song's name as typed in chat. With format set to text,
+// the bests are returned as a line that chat bots can relay, e.g. for a !pb command.
+func (s *server) personalBest(rctx *fasthttp.RequestCtx) {
+	action := "personal best"
+	args := rctx.QueryArgs()
+
+	song, ok := scoreSong(args)
+	if !ok {
+		rctx.Error(`{"error":"a song is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	scores, err := s.db.scores(ctx, song)
+	if err != nil {
+		log.Printf("%s - getting scores: %s", action, err)
+		rctx.Error(`{"error":"failed to get scores"}`, http.StatusInternalServerError)
+		return
+	}
+
+	bests := personalBests(scores)
+	if string(args.Peek("format")) == "text" {
+		rctx.SetContentType("text/plain; charset=utf-8")
+		rctx.WriteString(pbMessage(song, bests)) // nolint: errcheck
+		return
+	}
+
+	writeJSON(rctx, action, bests)
+}
+
+// scoreProgress handles requests for the streamer's scores on a song over time, as a
+// series of points for each instrument and difficulty. The song is identified the same
+// way as for personal bests, and instrument narrows the series down to one instrument.
+func (s *server) scoreProgress(rctx *fasthttp.RequestCtx) {
+	action := "score progress"
+	args := rctx.QueryArgs()
+
+	song, ok := scoreSong(args)
+	if !ok {
+		rctx.Error(`{"error":"a song is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	scores, err := s.db.scores(ctx, song)
+	if err != nil {
+		log.Printf("%s - getting scores: %s", action, err)
+		rctx.Error(`{"error":"failed to get scores"}`, http.StatusInternalServerError)
+		return
+	}
+	if instrument := string(args.Peek("instrument")); instrument != "" {
+		scores = slices.DeleteFunc(scores, func(sc *Score) bool { return sc.Instrument != instrument })
+	}
+
+	writeJSON(rctx, action, progress(scores))
+}
+
+// scoreSong returns the song identified by the artist and song query parameters, or by q
+// as typed in chat, reporting whether one was provided.
+func scoreSong(args *fasthttp.Args) (*Song, bool) {
+	if song := string(args.Peek("song")); song != "" {
+		return &Song{Artist: string(args.Peek("artist")), Name: song}, true
+	}
+	if q := strings.TrimSpace(string(args.Peek("q"))); q != "" {
+		return parseScoreSong(q), true
+	}
+
+	return nil, false
+}
+
+// getSession handles requests for the session with the provided id, or the started
+// session when no id is provided, along with the songs played during it.
+func (s *server) getSession(rctx *fasthttp.RequestCtx) {
+	action := "get session"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	session, err := s.db.session(ctx, string(rctx.QueryArgs().Peek("id")))
+	if err != nil {
+		log.Printf("%s - getting session: %s", action, err)
+		rctx.Error(`{"error":"failed to get session"}`, http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		rctx.Error(`{"error":"session not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if session.Plays, err = s.db.history(ctx, historyQuery{Stream: session.ID}); err != nil {
+		log.Printf("%s - getting history: %s", action, err)
+		rctx.Error(`{"error":"failed to get play history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, session)
+}
+
+// listSessions handles requests to list the streamer's sessions, most recent first.
+func (s *server) listSessions(rctx *fasthttp.RequestCtx) {
+	action := "list sessions"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	sessions, err := s.db.sessions(ctx)
+	if err != nil {
+		log.Printf("%s - getting sessions: %s", action, err)
+		rctx.Error(`{"error":"failed to get sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []*Session{}
+	}
+
+	writeJSON(rctx, action, sessions)
+}
+
+// startSession handles requests to start a session at the beginning of a stream. Only one
+// session can be started at a time.
+func (s *server) startSession(rctx *fasthttp.RequestCtx) {
+	action := "start session"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	session, err := startSession(ctx, s.db, time.Now())
+	if err != nil {
+		if errors.Is(err, errSessionOpen) {
+			writeError(rctx, http.StatusConflict, err.Error())
+			return
+		}
+		log.Printf("%s - %s", action, err)
+		rctx.Error(`{"error":"failed to start session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, session)
+}
+
+// stopSession handles requests to stop the started session at the end of a stream. The
+// songs played during it are archived to a setlist named after the day it started, e.g.
+// "stream 2024-03-09", and the temporary setlist is cleared.
+func (s *server) stopSession(rctx *fasthttp.RequestCtx) {
+	action := "stop session"
+
+	ctx, cancel := context.WithTimeout(rctx, 10*time.Second)
+	defer cancel()
+
+	session, err := stopSession(ctx, s.db, time.Now())
+	if err != nil {
+		if errors.Is(err, errNoSession) {
+			writeError(rctx, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("%s - %s", action, err)
+		rctx.Error(`{"error":"failed to stop session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := s.refresh(ctx, action, nil); err != nil {
+		log.Printf("%s - getting cleared setlist: %s", action, err)
+	}
+
+	writeJSON(rctx, action, session)
+}
+
+// getBlocklist handles requests for the streamer's blocklist.
+func (s *server) getBlocklist(rctx *fasthttp.RequestCtx) {
+	action := "get blocklist"
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	b, err := s.db.blocklist(ctx)
+	if err != nil {
+		log.Printf("%s - getting blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+	if b == nil {
+		b = &Blocklist{}
+	}
+
+	writeJSON(rctx, action, b)
+}
+
+// addBlock handles requests to block an artist, a song by providing both artist and song,
+// or songs matching a regular expression with pattern.
+func (s *server) addBlock(rctx *fasthttp.RequestCtx) {
+	s.updateBlocklist(rctx, "add block", (*Blocklist).add)
+}
+
+// removeBlock handles requests to unblock an artist, song or pattern, provided the same
+// way as when they were blocked.
+func (s *server) removeBlock(rctx *fasthttp.RequestCtx) {
+	s.updateBlocklist(rctx, "remove block", (*Blocklist).remove)
+}
+
+// updateBlocklist applies the update to the streamer's blocklist with the entry in the
+// request's artist, song and pattern parameters, then saves and returns the blocklist.
+func (s *server) updateBlocklist(rctx *fasthttp.RequestCtx, action string, update func(*Blocklist, *blockEntry) error) {
+	args := rctx.QueryArgs()
+	e := &blockEntry{
+		Artist:  string(args.Peek("artist")),
+		Song:    string(args.Peek("song")),
+		Pattern: string(args.Peek("pattern")),
+	}
+	if e.Song != "" && e.Pattern == "" && e.Artist == "" {
+		rctx.Error(`{"error":"an artist is required with a song"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	b, err := s.db.blocklist(ctx)
+	if err != nil {
+		log.Printf("%s - getting blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+	if b == nil {
+		b = &Blocklist{}
+	}
+
+	if err := update(b, e); err != nil {
+		writeError(rctx, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.db.block(ctx, b); err != nil {
+		log.Printf("%s - saving blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to save blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, b)
+}
+
+// limitBlocklist handles requests to set the longest song, in seconds with max_duration,
+// and the hardest song on an instrument, with instrument (guitar by default) and
+// max_difficulty, that can be added. A limit of 0 removes it.
+func (s *server) limitBlocklist(rctx *fasthttp.RequestCtx) {
+	action := "limit blocklist"
+	args := rctx.QueryArgs()
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	b, err := s.db.blocklist(ctx)
+	if err != nil {
+		log.Printf("%s - getting blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+	if b == nil {
+		b = &Blocklist{}
+	}
+
+	if args.Has("max_duration") {
+		if b.MaxDuration, err = args.GetUint("max_duration"); err != nil {
+			rctx.Error(`{"error":"max_duration must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if args.Has("max_difficulty") {
+		limit, err := args.GetUint("max_difficulty")
+		if err != nil {
+			rctx.Error(`{"error":"max_difficulty must be a whole number"}`, http.StatusBadRequest)
+			return
+		}
+
+		instrument := string(args.Peek("instrument"))
+		if instrument == "" {
+			instrument = defaultFilterInstrument
+		}
+		if limit == 0 {
+			maps.DeleteFunc(b.MaxDifficulty, func(i string, _ int) bool { return i == instrument })
+		} else {
+			if b.MaxDifficulty == nil {
+				b.MaxDifficulty = map[string]int{}
+			}
+			b.MaxDifficulty[instrument] = limit
+		}
+	}
+
+	if err := s.db.block(ctx, b); err != nil {
+		log.Printf("%s - saving blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to save blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, b)
+}
+
+// setCooldown handles requests to set how long after being played songs can't be
+// requested again, with the number of streams and minutes. Both default to 0, and a
+// cooldown of 0 streams and minutes removes it.
+func (s *server) setCooldown(rctx *fasthttp.RequestCtx) {
+	action := "set cooldown"
+	args := rctx.QueryArgs()
+
+	c := &Cooldown{}
+	for _, n := range []struct {
+		arg string
+		dst *int
+	}{
+		{"streams", &c.Streams},
+		{"minutes", &c.Minutes},
+	} {
+		if !args.Has(n.arg) {
+			continue
+		}
+		var err error
+		if *n.dst, err = args.GetUint(n.arg); err != nil {
+			writeError(rctx, http.StatusBadRequest, n.arg+" must be a whole number")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	b, err := s.db.blocklist(ctx)
+	if err != nil {
+		log.Printf("%s - getting blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to get blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+	if b == nil {
+		b = &Blocklist{}
+	}
+
+	b.Cooldown = c
+	if c.Streams == 0 && c.Minutes == 0 {
+		b.Cooldown = nil
+	}
+	if err := s.db.block(ctx, b); err != nil {
+		log.Printf("%s - saving blocklist: %s", action, err)
+		rctx.Error(`{"error":"failed to save blocklist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, b)
+}
+
+// getLibrary handles requests to list the charts in a streamer's library. Charts can be
+// filtered by artist, album, genre, charter, source, decade, max_duration and a
+// min_difficulty/max_difficulty range for an instrument (guitar by default).
+func (s *server) getLibrary(rctx *fasthttp.RequestCtx) {
+	action := "get library"
+
+	f, err := parseSongFilter(rctx.QueryArgs())
+	if err != nil {
+		writeError(rctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+	defer cancel()
+
+	library, err := s.db.library(ctx)
+	if err != nil {
+		log.Printf("%s - getting library: %s", action, err)
+		rctx.Error(`{"error":"failed to get library"}`, http.StatusInternalServerError)
+		return
+	}
+
+	charts := []*Chart{}
+	for _, c := range library {
+		if f.match(&c.Song) {
+			charts = append(charts, c)
+		}
+	}
+
+	writeJSON(rctx, action, charts)
+}
+
+// stockLibrary handles requests to replace a streamer's library with the JSON list of
+// charts sent as the request body. Charts are typically uploaded with the library
+// subcommand.
+func (s *server) stockLibrary(rctx *fasthttp.RequestCtx) {
+	action := "stock library"
+
+	var charts []*Chart
+	if err := json.Unmarshal(rctx.PostBody(), &charts); err != nil {
+		rctx.Error(`{"error":"body must be a list of charts"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(rctx, 30*time.Second)
+	defer cancel()
+
+	if err := s.db.stock(ctx, charts); err != nil {
+		log.Printf("%s - stocking library: %s", action, err)
+		rctx.Error(`{"error":"failed to update library"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(rctx, action, struct {
+		Charts int `json:"charts"`
+	}{len(charts)})
+}
+
+// findSong handles requests to look up a song. It looks up a particular song in a user's
+// stored song list and if not found, searches chorus to see if its chart is available to
+// download. It returns an applicable message based on its findings.
+// It currently is not in use and its implementation depends on a couple of factors:
+//   - Whether or not chorus allows external services to search its catalog
+//   - Whether or not a feature allowing users to upload their list of songs is
+//     implemented.
+// func findSong(ctx *fasthttp.RequestCtx) {}
+
+// findSongs handles requests to look up potenally multiple songs. It returns a list of
+// matched songs by partial name or artist name. Other parameters TBD. Also TBD is
+// pagination support in case there are more results provided than can be reasonably
+// returned.
+// It is also currently not in use and also depends on the above factors in the comment
+// for findSong
+// func findSongs(ctx *fasthttp.RequestCtx) {}
+
+// refresh looks up a setlist after it has been changed, summarizing it and notifying the
+// server's notifiers of its new state.
+func (s *server) refresh(ctx context.Context, action string, name []byte) (*Setlist, error) {
+	sl, err := setlist(ctx, s.db, name)
+	if err != nil {
+		return nil, err
+	}
+	if sl != nil {
+		sl.summarize(s.songGap)
+	}
+
+	s.notify(ctx, action, sl)
+
+	return sl, nil
+}
+
+// notify passes a setlist's new state along to the server's notifiers.
+func (s *server) notify(ctx context.Context, action string, sl *Setlist) {
+	for _, n := range s.notifiers {
+		if err := n.notify(ctx, sl); err != nil {
+			log.Printf("%s - notifying of setlist change: %s", action, err)
+		}
+	}
+}
+
+// writeJSON marshals the provided value and writes it as the response body.
+func writeJSON(rctx *fasthttp.RequestCtx, action string, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("%s - marshalling response data: %s", action, err)
+		rctx.Error(fmt.Sprintf("preparing response data: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	// TODO: Determine a good way of formatting data for output to chat.
+	rctx.SetContentType("application/json")
+	if _, err := rctx.Write(b); err != nil {
+		log.Printf("%s - writing response: %s", action, err)
+		rctx.Error(fmt.Sprintf("preparing response data: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeError writes a JSON error response with the provided message. It is used for
+// errors whose message isn't known ahead of time and so can't be written as a literal.
+func writeError(rctx *fasthttp.RequestCtx, status int, msg string) {
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{msg})
+	rctx.Error(string(b), status)
+}
 
 // healthcheck handles requests to inquire whether the service is running or not. It
 // currently returns no data, only an HTTP status code of 200 if successful.
@@ -38,8 +38,9 @@ func TestGetSetlist(t *testing.T) {
 						Name: "My Awesome Playlist",
 						Songs: []*Song{
 							{
-								Artist: "Dragonforce",
-								Name:   "Through the Fire and Flames",
+								Artist:   "Dragonforce",
+								Name:     "Through the Fire and Flames",
+								Duration: 441,
 							},
 						},
 					}, nil)
@@ -48,7 +49,8 @@ func TestGetSetlist(t *testing.T) {
 			},
 			expectedBody: `{` +
 				`"name":"My Awesome Playlist",` +
-				`"songs":[{"artist":"Dragonforce","name":"Through the Fire and Flames"}]` +
+				`"songs":[{"artist":"Dragonforce","name":"Through the Fire and Flames","duration":441}],` +
+				`"duration":441` +
 				`}`,
 			expectedStatusCode: http.StatusOK,
 		},
@@ -87,6 +89,385 @@ func TestGetSetlist(t *testing.T) {
 	}
 }
 
+func TestAddSong(t *testing.T) {
+	testCases := []struct {
+		name               string
+		params             string
+		db                 func(t *testing.T) *Mockdber
+		expectedBody       string
+		expectedStatusCode int
+		expectedNotified   []*Setlist
+	}{
+		{
+			name:   "adds song to temp setlist and returns updated setlist",
+			params: "?artist=Dragonforce&song=Through%20the%20Fire%20and%20Flames",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return(nil, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"}).
+					Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).
+					Return(&Setlist{
+						Name: tempSetlistName,
+						Songs: []*Song{
+							{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+						},
+					}, nil)
+
+				return db
+			},
+			expectedBody: `{` +
+				`"name":"temp",` +
+				`"songs":[{"artist":"Dragonforce","name":"Through the Fire and Flames"}],` +
+				`"unknown_durations":1` +
+				`}`,
+			expectedStatusCode: http.StatusOK,
+			expectedNotified: []*Setlist{
+				{
+					Name: tempSetlistName,
+					Songs: []*Song{
+						{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+					},
+					UnknownDurations: 1,
+				},
+			},
+		},
+		{
+			name:   "returns refusal reason when song is refused",
+			params: "?artist=Dragonforce&song=Through%20the%20Fire%20and%20Flames",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{
+					{Song: Song{Artist: "Dragonforce", Name: "Through the Fire and Flames", Duration: 441}},
+				}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).
+					Return(&Setlist{Name: tempSetlistName, Target: 300, TargetPolicy: targetPolicyRefuse}, nil)
+
+				return db
+			},
+			expectedBody:       `{"error":"adding \"Through the Fire and Flames\" would put \"temp\" 2m21s over its target length"}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:   "holds requests without a viewer for review",
+			params: "?artist=Dragonforce&song=Through%20the%20Fire%20and%20Flames",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("reviewing", mock.Anything).Return(true, nil)
+				db.On("library", mock.Anything).Return(nil, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("submit", mock.Anything, mock.AnythingOfType("*main.PendingRequest")).Return(nil)
+
+				return db
+			},
+			expectedBody:       "pending",
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:   "adds mods' requests without review",
+			params: "?artist=Dragonforce&song=Through%20the%20Fire%20and%20Flames&mod_token=hunter2",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(nil, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+				db.On("add", mock.Anything, tempSetlistName, &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"}).
+					Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).
+					Return(&Setlist{
+						Name:  tempSetlistName,
+						Songs: []*Song{{Artist: "Dragonforce", Name: "Through the Fire and Flames"}},
+					}, nil)
+
+				return db
+			},
+			expectedBody: `{` +
+				`"name":"temp",` +
+				`"songs":[{"artist":"Dragonforce","name":"Through the Fire and Flames"}],` +
+				`"unknown_durations":1` +
+				`}`,
+			expectedStatusCode: http.StatusOK,
+			expectedNotified: []*Setlist{
+				{
+					Name:             tempSetlistName,
+					Songs:            []*Song{{Artist: "Dragonforce", Name: "Through the Fire and Flames"}},
+					UnknownDurations: 1,
+				},
+			},
+		},
+		{
+			name:   "refuses override without the mod token",
+			params: "?artist=Slayer&song=Raining%20Blood&override=true",
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expectedBody:       `{"error":"only mods can override the cooldown"}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:   "returns bad request when song is missing",
+			params: "?artist=Dragonforce",
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expectedBody:       `{"error":"artist and song are required"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "returns error text when calling db errors",
+			params: "?name=Doomed%20Fingers&artist=Dragonforce&song=Soldiers%20of%20the%20Wasteland",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(nil, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers"}, nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Dragonforce", Name: "Soldiers of the Wasteland"}).
+					Return(fmt.Errorf("something broke"))
+
+				return db
+			},
+			expectedBody:       `{"error":"failed to add song"}`,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &testNotifier{}
+			s := newServer()
+			s.db = tc.db(t)
+			s.notifiers = []notifier{n}
+			s.modToken = []byte("hunter2")
+			client := newTestServer(t, s.addSong)
+
+			resp, err := client.Get(lh + tc.params)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			require.NotNil(t, resp.Body)
+			respBody, _ := io.ReadAll(resp.Body)
+			if tc.expectedStatusCode == http.StatusAccepted {
+				// pending requests are given a new ID, so only their shape is checked
+				assert.Contains(t, string(respBody), `"pending":{"id":"`)
+				return
+			}
+			assert.Equal(t, tc.expectedBody, string(respBody))
+			assert.Equal(t, tc.expectedNotified, n.setlists)
+		})
+	}
+}
+
+func TestSpinWheel(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	downFromTheSky := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+	library := []*Chart{{Song: *fatLip}, {Song: *downFromTheSky}}
+
+	testCases := []struct {
+		name               string
+		params             string
+		db                 func(t *testing.T) *Mockdber
+		expectedBody       string
+		expectedStatusCode int
+	}{
+		{
+			name:   "excludes songs played on open session",
+			params: "?seed=1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName}, nil)
+				db.On("session", mock.Anything, "").Return(&Session{ID: "65f0c0ffee"}, nil)
+				db.On("history", mock.Anything, historyQuery{Stream: "65f0c0ffee"}).Return([]*Play{{Song: fatLip}}, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: wheelHistoryStreams}).Return([]*Play{{Song: fatLip}}, nil)
+
+				return db
+			},
+			expectedBody: `{"type":"wheel_spin","segments":[{"artist":"Trivium","name":"Down From the Sky"}],` +
+				`"pick":0,"song":{"artist":"Trivium","name":"Down From the Sky"},"added":false}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "excludes nothing played without open session",
+			params: "?setlist=chill&seed=1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{fatLip}}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName}, nil)
+				db.On("session", mock.Anything, "").Return(nil, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: wheelHistoryStreams}).Return([]*Play{{Song: fatLip}}, nil)
+
+				return db
+			},
+			expectedBody: `{"type":"wheel_spin","segments":[{"artist":"Sum 41","name":"Fat Lip"}],` +
+				`"pick":0,"song":{"artist":"Sum 41","name":"Fat Lip"},"added":false}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:   "returns not found when blocked and queued songs leave nothing to pick",
+			params: "?seed=1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(&Blocklist{Artists: []string{"Trivium"}}, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{fatLip}}, nil)
+				db.On("session", mock.Anything, "").Return(nil, nil)
+				db.On("history", mock.Anything, historyQuery{Streams: wheelHistoryStreams}).Return(nil, nil)
+
+				return db
+			},
+			expectedBody:       `{"error":"no songs can be picked"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:   "returns not found when setlist doesn't exist",
+			params: "?setlist=chill",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(nil, nil)
+
+				return db
+			},
+			expectedBody:       `{"error":"setlist not found"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:   "returns error text when looking up open session errors",
+			params: "?seed=1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName}, nil)
+				db.On("session", mock.Anything, "").Return(nil, fmt.Errorf("something broke"))
+
+				return db
+			},
+			expectedBody:       `{"error":"failed to get session"}`,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "returns bad request when segments isn't a number",
+			params:             "?segments=lots",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedBody:       `{"error":"segments must be a whole number"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, s.spinWheel)
+
+			resp, err := client.Get(lh + tc.params)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(respBody))
+		})
+	}
+}
+
+func TestExportSetlist(t *testing.T) {
+	testCases := []struct {
+		name                string
+		params              string
+		db                  func(t *testing.T) *Mockdber
+		expectedBody        string
+		expectedStatusCode  int
+		expectedDisposition string
+	}{
+		{
+			name:   "exports setlist in requested format",
+			params: "?name=Doomed%20Fingers&format=text",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "Doomed Fingers").
+					Return(&Setlist{
+						Name: "Doomed Fingers",
+						Songs: []*Song{
+							{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+						},
+					}, nil)
+
+				return db
+			},
+			expectedBody:        "Doomed Fingers\n\n1. Dragonforce - Through the Fire and Flames\n",
+			expectedStatusCode:  http.StatusOK,
+			expectedDisposition: `attachment; filename="Doomed Fingers.txt"`,
+		},
+		{
+			name:   "returns bad request for unknown format",
+			params: "?name=Doomed%20Fingers&format=xlsx",
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expectedBody:       `{"error":"format must be one of csv, markdown, m3u or text"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "returns not found when setlist does not exist",
+			params: "?name=Djent%20Madness&format=csv",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "Djent Madness").Return(nil, nil)
+
+				return db
+			},
+			expectedBody:       `{"error":"setlist not found"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, s.exportSetlist)
+
+			resp, err := client.Get(lh + tc.params)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.expectedDisposition, resp.Header.Get("Content-Disposition"))
+			require.NotNil(t, resp.Body)
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(respBody))
+		})
+	}
+}
+
+// testNotifier records the setlists it is notified of.
+type testNotifier struct {
+	setlists []*Setlist
+}
+
+func (n *testNotifier) notify(ctx context.Context, sl *Setlist) error {
+	n.setlists = append(n.setlists, sl)
+	return nil
+}
+
 // newTestServer configures an in memory listener (server) with the provided handler and
 // returns a client to use to make requests against the server with.
 func newTestServer(t *testing.T, h fasthttp.RequestHandler) *http.Client {
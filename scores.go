@@ -0,0 +1,181 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultScoreInstrument = "guitar"
+	defaultScoreDifficulty = "expert"
+)
+
+// errNotPlayed is returned when attaching a score to a song that wasn't played on the
+// current stream.
+var errNotPlayed = errors.New("song hasn't been played this stream")
+
+// Score is the streamer's result from playing a song.
+type Score struct {
+	ID   string `json:"id"`
+	Song *Song  `json:"song"`
+	// Stream and PlayedAt identify the play the score is attached to.
+	Stream   string    `json:"stream"`
+	PlayedAt time.Time `json:"played_at"`
+	Score    int       `json:"score"`
+	// Accuracy is the percentage of notes hit.
+	Accuracy   float64 `json:"accuracy"`
+	Stars      int     `json:"stars"`
+	FullCombo  bool    `json:"fc"`
+	Instrument string  `json:"instrument"`
+	Difficulty string  `json:"difficulty"`
+}
+
+// better reports whether the score beats the other, going by score and then accuracy.
+func (sc *Score) better(other *Score) bool {
+	if sc.Score != other.Score {
+		return sc.Score > other.Score
+	}
+	return sc.Accuracy > other.Accuracy
+}
+
+// attachScore attaches the score to the most recent play on the current stream of the
+// score's song, or of the last song played if the score doesn't have one, and records
+// it. errNotPlayed is returned if there's no such play.
+func attachScore(ctx context.Context, db scoreKeeper, sc *Score) error {
+	plays, err := db.history(ctx, historyQuery{Streams: 1})
+	if err != nil {
+		return fmt.Errorf("looking up history: %w", err)
+	}
+
+	var play *Play
+	for _, p := range plays {
+		if sc.Song != nil && songKey(p.Song) != songKey(sc.Song) {
+			continue
+		}
+		if play == nil || p.PlayedAt.After(play.PlayedAt) {
+			play = p
+		}
+	}
+	if play == nil {
+		return errNotPlayed
+	}
+
+	sc.ID = primitive.NewObjectID().Hex()
+	sc.Song = &Song{Artist: play.Song.Artist, Name: play.Song.Name}
+	sc.Stream, sc.PlayedAt = play.Stream, play.PlayedAt
+	if err := db.record(ctx, sc); err != nil {
+		return fmt.Errorf("recording score: %w", err)
+	}
+
+	return nil
+}
+
+// personalBests returns the best of the scores for each instrument and difficulty, ordered
+// by instrument and then difficulty.
+func personalBests(scores []*Score) []*Score {
+	best := map[[2]string]*Score{}
+	for _, sc := range scores {
+		k := [2]string{sc.Instrument, sc.Difficulty}
+		if b, ok := best[k]; !ok || sc.better(b) {
+			best[k] = sc
+		}
+	}
+
+	bests := make([]*Score, 0, len(best))
+	for _, sc := range best {
+		bests = append(bests, sc)
+	}
+	slices.SortFunc(bests, func(a, b *Score) int {
+		return cmp.Or(cmp.Compare(a.Instrument, b.Instrument), cmp.Compare(a.Difficulty, b.Difficulty))
+	})
+
+	return bests
+}
+
+// ScoreSeries is the progress on a song on an instrument and difficulty over time, ready
+// to be charted.
+type ScoreSeries struct {
+	Instrument string        `json:"instrument"`
+	Difficulty string        `json:"difficulty"`
+	Points     []*ScorePoint `json:"points"`
+}
+
+// ScorePoint is a score in a ScoreSeries.
+type ScorePoint struct {
+	PlayedAt  time.Time `json:"played_at"`
+	Score     int       `json:"score"`
+	Accuracy  float64   `json:"accuracy"`
+	Stars     int       `json:"stars"`
+	FullCombo bool      `json:"fc"`
+}
+
+// progress returns a series for each instrument and difficulty the song was scored on, with
+// points ordered by when they were played.
+func progress(scores []*Score) []*ScoreSeries {
+	scores = slices.Clone(scores)
+	slices.SortStableFunc(scores, func(a, b *Score) int { return a.PlayedAt.Compare(b.PlayedAt) })
+
+	series := []*ScoreSeries{}
+	for _, pb := range personalBests(scores) {
+		ss := &ScoreSeries{Instrument: pb.Instrument, Difficulty: pb.Difficulty}
+		for _, sc := range scores {
+			if sc.Instrument == ss.Instrument && sc.Difficulty == ss.Difficulty {
+				ss.Points = append(ss.Points, &ScorePoint{
+					PlayedAt:  sc.PlayedAt,
+					Score:     sc.Score,
+					Accuracy:  sc.Accuracy,
+					Stars:     sc.Stars,
+					FullCombo: sc.FullCombo,
+				})
+			}
+		}
+		series = append(series, ss)
+	}
+
+	return series
+}
+
+// parseScoreSong parses a song looked up by chat, either "Artist - Song" or just the song's
+// name. The artist is left empty when only a name is provided.
+func parseScoreSong(input string) *Song {
+	if artist, name, ok := parseSongInput(input); ok {
+		return &Song{Artist: artist, Name: name}
+	}
+
+	return &Song{Name: strings.TrimSpace(input)}
+}
+
+// pbMessage formats the personal bests on a song as a single line for chat.
+func pbMessage(s *Song, bests []*Score) string {
+	name := s.Name
+	if s.Artist != "" {
+		name = chatSong(s)
+	}
+	if len(bests) == 0 {
+		return fmt.Sprintf("No scores on %s yet", name)
+	}
+	if s.Artist == "" {
+		name = chatSong(bests[0].Song)
+	}
+
+	var pbs []string
+	for _, sc := range bests {
+		details := []string{fmt.Sprintf("%.2f%%", sc.Accuracy), fmt.Sprintf("%d★", sc.Stars)}
+		if sc.FullCombo {
+			details = append(details, "FC")
+		}
+		pbs = append(pbs, fmt.Sprintf(
+			"%s %s %d (%s)",
+			sc.Difficulty, sc.Instrument, sc.Score, strings.Join(details, ", "),
+		))
+	}
+
+	return fmt.Sprintf("PB on %s: %s", name, strings.Join(pbs, " | "))
+}
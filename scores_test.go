@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachScore(t *testing.T) {
+	playedAt := time.Date(2024, 3, 9, 20, 0, 0, 0, time.UTC)
+	plays := []*Play{
+		{Song: &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}, Stream: "a", PlayedAt: playedAt},
+		{Song: &Song{Artist: "Trivium", Name: "Down From the Sky"}, Stream: "a", PlayedAt: playedAt.Add(5 * time.Minute)},
+	}
+
+	testCases := []struct {
+		name         string
+		song         *Song
+		expectedSong *Song
+		expectedAt   time.Time
+		expectedErr  error
+	}{
+		{
+			name:         "attaches to played song",
+			song:         &Song{Artist: "sum 41", Name: "fat lip"},
+			expectedSong: &Song{Artist: "Sum 41", Name: "Fat Lip"},
+			expectedAt:   playedAt,
+		},
+		{
+			name:         "attaches to last song played",
+			expectedSong: &Song{Artist: "Trivium", Name: "Down From the Sky"},
+			expectedAt:   playedAt.Add(5 * time.Minute),
+		},
+		{
+			name:        "errors for song not played",
+			song:        &Song{Artist: "Slayer", Name: "Raining Blood"},
+			expectedErr: errNotPlayed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := NewMockdber(t)
+			db.On("history", mock.Anything, historyQuery{Streams: 1}).Return(plays, nil)
+			if tc.expectedErr == nil {
+				db.On("record", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			sc := &Score{Song: tc.song, Score: 123456}
+			err := attachScore(context.Background(), db, sc)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, sc.ID)
+			assert.Equal(t, tc.expectedSong, sc.Song)
+			assert.Equal(t, "a", sc.Stream)
+			assert.Equal(t, tc.expectedAt, sc.PlayedAt)
+		})
+	}
+}
+
+func TestPersonalBestsAndProgress(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	at := func(day int) time.Time { return time.Date(2024, 3, day, 20, 0, 0, 0, time.UTC) }
+	scores := []*Score{
+		{Song: fatLip, PlayedAt: at(9), Score: 150000, Accuracy: 97.5, Stars: 5, Instrument: "guitar", Difficulty: "expert"},
+		{Song: fatLip, PlayedAt: at(2), Score: 120000, Accuracy: 91, Stars: 4, Instrument: "guitar", Difficulty: "expert"},
+		{Song: fatLip, PlayedAt: at(16), Score: 150000, Accuracy: 99.25, Stars: 6, FullCombo: true, Instrument: "guitar", Difficulty: "expert"},
+		{Song: fatLip, PlayedAt: at(3), Score: 90000, Accuracy: 88, Stars: 4, Instrument: "drums", Difficulty: "hard"},
+	}
+
+	bests := personalBests(scores)
+	assert.Equal(t, []*Score{scores[3], scores[2]}, bests)
+	assert.Equal(t,
+		"PB on Sum 41 - Fat Lip: hard drums 90000 (88.00%, 4★) | expert guitar 150000 (99.25%, 6★, FC)",
+		pbMessage(&Song{Name: "fat lip"}, bests),
+	)
+	assert.Equal(t, "No scores on fat lip yet", pbMessage(&Song{Name: "fat lip"}, nil))
+
+	assert.Equal(t, []*ScoreSeries{
+		{
+			Instrument: "drums",
+			Difficulty: "hard",
+			Points:     []*ScorePoint{{PlayedAt: at(3), Score: 90000, Accuracy: 88, Stars: 4}},
+		},
+		{
+			Instrument: "guitar",
+			Difficulty: "expert",
+			Points: []*ScorePoint{
+				{PlayedAt: at(2), Score: 120000, Accuracy: 91, Stars: 4},
+				{PlayedAt: at(9), Score: 150000, Accuracy: 97.5, Stars: 5},
+				{PlayedAt: at(16), Score: 150000, Accuracy: 99.25, Stars: 6, FullCombo: true},
+			},
+		},
+	}, progress(scores))
+}
+
+func TestPersonalBest(t *testing.T) {
+	db := NewMockdber(t)
+	db.On("scores", mock.Anything, &Song{Artist: "Sum 41", Name: "Fat Lip"}).Return([]*Score{
+		{Song: &Song{Artist: "Sum 41", Name: "Fat Lip"}, Score: 150000, Accuracy: 97.5, Stars: 5, Instrument: "guitar", Difficulty: "expert"},
+	}, nil)
+
+	s := newServer()
+	s.db = db
+	client := newTestServer(t, routes(s).Handler)
+
+	resp, err := client.Get(lh + "/v1/scores/pb?q=Sum%2041%20-%20Fat%20Lip&format=text")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "PB on Sum 41 - Fat Lip: expert guitar 150000 (97.50%, 5★)", string(body))
+
+	resp, err = client.Get(lh + "/v1/scores/pb")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
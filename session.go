@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// errSessionOpen is returned when starting a session while one is already open.
+	errSessionOpen = errors.New("a session is already started")
+	// errNoSession is returned when stopping a session while none is open.
+	errNoSession = errors.New("no session is started")
+)
+
+// Session is one of a streamer's streams, from when it's started to when it's stopped.
+type Session struct {
+	ID        string     `json:"id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	// Setlist is the name of the setlist the songs played during the session were archived
+	// to when it was stopped, if any were played.
+	Setlist string `json:"setlist,omitempty"`
+	// Plays are the songs played during the session, included when viewing a session
+	// rather than stored with it.
+	Plays []*Play `json:"plays,omitempty"`
+}
+
+// startSession opens a new session starting at now, failing with errSessionOpen if one is
+// already open.
+func startSession(ctx context.Context, db sessioner, now time.Time) (*Session, error) {
+	open, err := db.session(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("looking up open session: %w", err)
+	}
+	if open != nil {
+		return nil, errSessionOpen
+	}
+
+	s := &Session{ID: primitive.NewObjectID().Hex(), StartedAt: now.UTC()}
+	if err := db.start(ctx, s); err != nil {
+		return nil, fmt.Errorf("starting session: %w", err)
+	}
+
+	return s, nil
+}
+
+// stopSession closes the open session at now, failing with errNoSession if none is open.
+// The songs played during the session are archived to a setlist named after the day it
+// started and the temporary setlist is cleared for the next one.
+func stopSession(ctx context.Context, db archiver, now time.Time) (*Session, error) {
+	s, err := db.session(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("looking up open session: %w", err)
+	}
+	if s == nil {
+		return nil, errNoSession
+	}
+
+	if s.Plays, err = db.history(ctx, historyQuery{Stream: s.ID}); err != nil {
+		return nil, fmt.Errorf("looking up plays: %w", err)
+	}
+	if len(s.Plays) > 0 {
+		if s.Setlist, err = archiveName(ctx, db, s.StartedAt); err != nil {
+			return nil, err
+		}
+
+		songs := make([]*Song, 0, len(s.Plays))
+		for _, p := range s.Plays {
+			songs = append(songs, p.Song)
+		}
+		if err := replace(ctx, db, s.Setlist, songs); err != nil {
+			return nil, fmt.Errorf("archiving plays: %w", err)
+		}
+	}
+
+	if err := db.clear(ctx, tempSetlistName); err != nil {
+		return nil, fmt.Errorf("clearing setlist: %w", err)
+	}
+
+	ended := now.UTC()
+	s.EndedAt = &ended
+	if err := db.stop(ctx, s); err != nil {
+		return nil, fmt.Errorf("stopping session: %w", err)
+	}
+
+	return s, nil
+}
+
+// archiveName returns the name of the setlist a session started at the provided time is
+// archived to, e.g. "stream 2024-03-09", numbering it when there's already a setlist
+// with the name from another session that day.
+func archiveName(ctx context.Context, db finder, startedAt time.Time) (string, error) {
+	base := "stream " + startedAt.Format(time.DateOnly)
+	name := base
+	for i := 2; ; i++ {
+		sl, err := db.find(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("looking up setlist: %w", err)
+		}
+		if sl == nil {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+}
+
+// playNext marks the song being played on the temporary setlist as played and moves on to
+// the next one, returning the played song or nil if the setlist is empty. The play is
+// recorded when a session is open.
+func playNext(ctx context.Context, db advancer, now time.Time) (*Song, error) {
+	sl, err := db.find(ctx, tempSetlistName)
+	if err != nil {
+		return nil, fmt.Errorf("looking up setlist: %w", err)
+	}
+	if sl == nil || len(sl.Songs) == 0 {
+		return nil, nil
+	}
+	played := sl.Songs[0]
+
+	s, err := db.session(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("looking up open session: %w", err)
+	}
+	if s != nil {
+		if err := db.play(ctx, &Play{Song: played, Stream: s.ID, PlayedAt: now.UTC()}); err != nil {
+			return nil, fmt.Errorf("recording play: %w", err)
+		}
+	}
+
+	if err := db.remove(ctx, tempSetlistName, "", 1); err != nil {
+		return nil, fmt.Errorf("removing song: %w", err)
+	}
+
+	return played, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopSession(t *testing.T) {
+	startedAt := time.Date(2024, 3, 9, 19, 0, 0, 0, time.UTC)
+	now := startedAt.Add(3 * time.Hour)
+	open := func() *Session { return &Session{ID: "65ecb0c0ffee", StartedAt: startedAt} }
+	plays := []*Play{
+		{Song: &Song{Artist: "Sum 41", Name: "Fat Lip"}, Stream: "65ecb0c0ffee", PlayedAt: startedAt.Add(time.Hour)},
+		{Song: &Song{Artist: "Trivium", Name: "Down From the Sky"}, Stream: "65ecb0c0ffee", PlayedAt: startedAt.Add(2 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name            string
+		db              func(t *testing.T) *Mockdber
+		expectedSetlist string
+		expectedErr     error
+	}{
+		{
+			name: "archives plays to dated setlist",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(open(), nil)
+				db.On("history", mock.Anything, historyQuery{Stream: "65ecb0c0ffee"}).Return(plays, nil)
+				db.On("find", mock.Anything, "stream 2024-03-09").Return(nil, nil)
+				db.On("create", mock.Anything, "stream 2024-03-09").Return(&Setlist{Name: "stream 2024-03-09"}, nil)
+				db.On("add", mock.Anything, "stream 2024-03-09", plays[0].Song).Return(nil)
+				db.On("add", mock.Anything, "stream 2024-03-09", plays[1].Song).Return(nil)
+				db.On("clear", mock.Anything, tempSetlistName).Return(nil)
+				db.On("stop", mock.Anything, mock.MatchedBy(func(s *Session) bool {
+					return s.EndedAt.Equal(now) && s.Setlist == "stream 2024-03-09"
+				})).Return(nil)
+
+				return db
+			},
+			expectedSetlist: "stream 2024-03-09",
+		},
+		{
+			name: "numbers setlist after another stream that day",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(open(), nil)
+				db.On("history", mock.Anything, historyQuery{Stream: "65ecb0c0ffee"}).Return(plays[:1], nil)
+				db.On("find", mock.Anything, "stream 2024-03-09").Return(&Setlist{Name: "stream 2024-03-09"}, nil)
+				db.On("find", mock.Anything, "stream 2024-03-09 (2)").Return(nil, nil).Once()
+				db.On("create", mock.Anything, "stream 2024-03-09 (2)").Return(&Setlist{Name: "stream 2024-03-09 (2)"}, nil)
+				db.On("find", mock.Anything, "stream 2024-03-09 (2)").Return(nil, nil)
+				db.On("add", mock.Anything, "stream 2024-03-09 (2)", plays[0].Song).Return(nil)
+				db.On("clear", mock.Anything, tempSetlistName).Return(nil)
+				db.On("stop", mock.Anything, mock.Anything).Return(nil)
+
+				return db
+			},
+			expectedSetlist: "stream 2024-03-09 (2)",
+		},
+		{
+			name: "doesn't archive session without plays",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(open(), nil)
+				db.On("history", mock.Anything, historyQuery{Stream: "65ecb0c0ffee"}).Return(nil, nil)
+				db.On("clear", mock.Anything, tempSetlistName).Return(nil)
+				db.On("stop", mock.Anything, mock.Anything).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name: "errors without open session",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(nil, nil)
+
+				return db
+			},
+			expectedErr: errNoSession,
+		},
+		{
+			name: "db returns error when clearing setlist",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("session", mock.Anything, "").Return(open(), nil)
+				db.On("history", mock.Anything, historyQuery{Stream: "65ecb0c0ffee"}).Return(nil, nil)
+				db.On("clear", mock.Anything, tempSetlistName).Return(fmt.Errorf("it broke"))
+
+				return db
+			},
+			expectedErr: fmt.Errorf("clearing setlist: it broke"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := stopSession(context.Background(), tc.db(t), now)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSetlist, s.Setlist)
+		})
+	}
+}
+
+func TestPlayNext(t *testing.T) {
+	now := time.Date(2024, 3, 9, 20, 0, 0, 0, time.UTC)
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	queued := &Setlist{Name: tempSetlistName, Songs: []*Song{fatLip, {Artist: "Trivium", Name: "Down From the Sky"}}}
+
+	t.Run("records play during session", func(t *testing.T) {
+		db := NewMockdber(t)
+		db.On("find", mock.Anything, tempSetlistName).Return(queued, nil)
+		db.On("session", mock.Anything, "").Return(&Session{ID: "65ecb0c0ffee"}, nil)
+		db.On("play", mock.Anything, &Play{Song: fatLip, Stream: "65ecb0c0ffee", PlayedAt: now}).Return(nil)
+		db.On("remove", mock.Anything, tempSetlistName, "", 1).Return(nil)
+
+		played, err := playNext(context.Background(), db, now)
+		require.NoError(t, err)
+		assert.Equal(t, fatLip, played)
+	})
+
+	t.Run("moves on without session", func(t *testing.T) {
+		db := NewMockdber(t)
+		db.On("find", mock.Anything, tempSetlistName).Return(queued, nil)
+		db.On("session", mock.Anything, "").Return(nil, nil)
+		db.On("remove", mock.Anything, tempSetlistName, "", 1).Return(nil)
+
+		played, err := playNext(context.Background(), db, now)
+		require.NoError(t, err)
+		assert.Equal(t, fatLip, played)
+	})
+
+	t.Run("returns nil when nothing is playing", func(t *testing.T) {
+		db := NewMockdber(t)
+		db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName}, nil)
+
+		played, err := playNext(context.Background(), db, now)
+		require.NoError(t, err)
+		assert.Nil(t, played)
+	})
+}
+
+func TestStartSession(t *testing.T) {
+	db := NewMockdber(t)
+	db.On("session", mock.Anything, "").Return(nil, nil).Once()
+	db.On("start", mock.Anything, mock.MatchedBy(func(s *Session) bool {
+		return s.ID != "" && !s.StartedAt.IsZero()
+	})).Return(nil)
+	db.On("session", mock.Anything, "").Return(&Session{ID: "65ecb0c0ffee"}, nil)
+
+	s := newServer()
+	s.db = db
+	client := newTestServer(t, routes(s).Handler)
+
+	resp, err := client.Get(lh + "/v1/session/start")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// starting again conflicts with the open session
+	resp, err = client.Get(lh + "/v1/session/start")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"error":"a session is already started"}`, string(body))
+}
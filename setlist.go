@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,6 +14,31 @@ const (
 	tempSetlistName = "temp"
 )
 
+// targetPolicy determines what happens when adding a song would push a setlist past its
+// target length.
+type targetPolicy string
+
+const (
+	// targetPolicyWarn adds the song anyway, warning that the setlist is over its target.
+	targetPolicyWarn targetPolicy = "warn"
+	// targetPolicyRefuse refuses to add the song.
+	targetPolicyRefuse targetPolicy = "refuse"
+)
+
+// errMissingSong is returned when a request to modify a setlist's songs doesn't identify
+// a song.
+var errMissingSong = errors.New("song not provided")
+
+// refusalError is returned when a request is refused for a reason that should be relayed
+// back to whoever made it, e.g. by a chat bot.
+type refusalError struct {
+	reason string
+}
+
+func (e *refusalError) Error() string {
+	return e.reason
+}
+
 // Setlist represents data about a setlist including how long it should remain available.
 // For temporary setlists, their expiry will be set to creation time plus the
 // temporaryPlaylistLifespan. For persisted setlists, their expiry may not be set or may
@@ -21,21 +48,84 @@ type Setlist struct {
 	Name   string             `json:"name"`
 	Expiry time.Time          `json:"-"`
 	Songs  []*Song            `json:"songs,omitempty"`
+	// Tags are free-form labels used to organize persisted setlists, e.g. "metal".
+	Tags []string `json:"tags,omitempty"`
+	// Target is the length in seconds the setlist is meant to fit in, if any.
+	Target       int          `json:"target,omitempty"`
+	TargetPolicy targetPolicy `json:"target_policy,omitempty"`
+
+	// The following are estimated when a setlist is returned rather than stored. See
+	// summarize.
+
+	// Duration is the setlist's expected length in seconds.
+	Duration int `json:"duration,omitempty"`
+	// Remaining is the number of seconds left until the setlist reaches its target, which
+	// is negative when the setlist is over its target.
+	Remaining *int `json:"remaining,omitempty"`
+	// UnknownDurations is the number of songs without a duration, which aren't included in
+	// the estimated length.
+	UnknownDurations int `json:"unknown_durations,omitempty"`
+}
+
+// estimate returns the setlist's expected length in seconds, counting gap seconds between
+// each song, along with the number of songs whose durations aren't known.
+func (sl *Setlist) estimate(gap int) (total, unknown int) {
+	for i, s := range sl.Songs {
+		if i > 0 {
+			total += gap
+		}
+		if s.Duration == 0 {
+			unknown++
+		}
+		total += s.Duration
+	}
+
+	return total, unknown
+}
+
+// summarize fills in the setlist's estimated fields.
+func (sl *Setlist) summarize(gap int) {
+	sl.Duration, sl.UnknownDurations = sl.estimate(gap)
+
+	sl.Remaining = nil
+	if sl.Target > 0 {
+		remaining := sl.Target - sl.Duration
+		sl.Remaining = &remaining
+	}
 }
 
-// Song represents data about a particular song
+// Song represents data about a particular song. Apart from its artist and name, a song's
+// details are optional and are typically filled in from the streamer's library.
 type Song struct {
-	Artist string `json:"artist"`
-	Name   string `json:"name"`
+	Artist  string `json:"artist"`
+	Name    string `json:"name"`
+	Album   string `json:"album,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	Genre   string `json:"genre,omitempty"`
+	Charter string `json:"charter,omitempty"`
+	// Duration is the song's length in seconds.
+	Duration int `json:"duration,omitempty"`
+	// Source is the game or chart pack the song's chart originally comes from.
+	Source string `json:"source,omitempty"`
+	// Difficulties contains the chart's difficulty rating for each charted instrument,
+	// keyed by instrument name, e.g. guitar or drums.
+	Difficulties map[string]int `json:"difficulties,omitempty"`
+	// ChartURL is an optional link to where the song's chart can be downloaded.
+	ChartURL string `json:"chart_url,omitempty"`
+	// Priority is the song's priority tier, with higher tiers played first. Songs without
+	// a priority are in tier zero.
+	Priority int `json:"priority,omitempty"`
+	// RequestedBy is the viewer who requested the song, if known.
+	RequestedBy string `json:"requested_by,omitempty"`
+	// RequestedAt is when a viewer's request was queued, used to tell how long it waited
+	// to be played.
+	RequestedAt *time.Time `json:"requested_at,omitempty"`
 }
 
 // TODO: update to include collection
 func setlist(ctx context.Context, db finderCreator, name []byte) (*Setlist, error) {
-	slName := string(name)
 	// if no name is provided, try looking up the temporary setlist
-	if slName == "" {
-		slName = tempSetlistName
-	}
+	slName := setlistName(name)
 
 	sl, err := db.find(ctx, slName)
 	if err != nil {
@@ -58,11 +148,22 @@ func setlist(ctx context.Context, db finderCreator, name []byte) (*Setlist, erro
 	return sl, nil
 }
 
-func delete(ctx context.Context, db deleter, name []byte) error {
+// delete moves a setlist to the trash, where it can be restored from until expires.
+func delete(ctx context.Context, db deleter, name []byte, expires time.Time) error {
+	if err := db.delete(ctx, string(name), expires); err != nil {
+		return fmt.Errorf("deleting setlist: %w", err)
+	}
+
 	return nil
 }
 
+// clear removes all songs from a setlist. If no name is provided, the temporary setlist
+// will be cleared.
 func clear(ctx context.Context, db clearer, name []byte) error {
+	if err := db.clear(ctx, setlistName(name)); err != nil {
+		return fmt.Errorf("clearing setlist: %w", err)
+	}
+
 	return nil
 }
 
@@ -75,10 +176,182 @@ func update(ctx context.Context, db updater, name []byte) error {
 	return nil
 }
 
-func addSong(ctx context.Context, db songer, name []byte) error {
+// songRequest describes a request to add a song to a setlist.
+type songRequest struct {
+	// Setlist is the name of the setlist to add to. The temporary setlist is used when
+	// empty.
+	Setlist string
+	Artist  string
+	Song    string
+	// Gap is the number of seconds expected between songs, used when checking whether the
+	// song fits in the setlist's target length.
+	Gap int
+	// Priority is the tier the song is queued in. Songs are added after all songs in the
+	// same or higher tiers, so each tier is played in the order it was requested.
+	Priority int
+	// User is the viewer requesting the song, if known.
+	User string
+	// Trusted skips the review queue, for the streamer and their mods. It must only be set
+	// for requests whose sender has been checked, never because a field is missing.
+	Trusted bool
+	// Override skips the streamer's replay cooldown, for the streamer and their mods.
+	Override bool
+	// RequestedAt is when the viewer made the request, if it was held for review before
+	// being added. It's the time the song is added otherwise.
+	RequestedAt time.Time
+}
+
+// addSong queues the requested song on a setlist, behind any songs with the same or a
+// higher priority. When the song is in the streamer's library its details are filled in
+// from there. Songs on the streamer's blocklist or played too recently for their replay
+// cooldown are refused with a refusalError. If the song would push the setlist past its
+// target length, it is either refused or added with a warning depending on the setlist's
+// target policy. The song added is returned, along with any warnings.
+func addSong(ctx context.Context, db songAdder, r *songRequest) (*Song, []string, error) {
+	if r.Artist == "" || r.Song == "" {
+		return nil, nil, errMissingSong
+	}
+
+	name := setlistName([]byte(r.Setlist))
+	s := &Song{Artist: r.Artist, Name: r.Song}
+
+	library, err := db.library(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("looking up library: %w", err)
+	}
+	if c := findChart(library, s); c != nil {
+		details := c.Song
+		s = &details
+	}
+	if err := checkBlocklist(ctx, db, s, r.Override); err != nil {
+		return nil, nil, err
+	}
+
+	sl, err := db.find(ctx, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("looking up setlist: %w", err)
+	}
+
+	var warnings []string
+	if over := overTarget(sl, s, r.Gap); over > 0 {
+		msg := fmt.Sprintf(
+			"adding %q would put %q %s over its target length",
+			s.Name, sl.Name, time.Duration(over)*time.Second,
+		)
+		if sl.TargetPolicy == targetPolicyRefuse {
+			return nil, nil, &refusalError{reason: msg}
+		}
+		warnings = append(warnings, msg)
+	}
+
+	s.Priority = r.Priority
+	if r.User != "" {
+		at := r.RequestedAt
+		if at.IsZero() {
+			at = time.Now().UTC()
+		}
+		s.RequestedBy, s.RequestedAt = r.User, &at
+	}
+	if err := db.add(ctx, name, s); err != nil {
+		return nil, nil, fmt.Errorf("adding song: %w", err)
+	}
+
+	if sl != nil {
+		if from, to := len(sl.Songs)+1, queuePosition(sl, r.Priority); to < from {
+			if err := db.move(ctx, name, from, to); err != nil {
+				return nil, nil, fmt.Errorf("moving song to its priority: %w", err)
+			}
+		}
+	}
+
+	return s, warnings, nil
+}
+
+// queuePosition returns the 1-based position a song in the provided priority tier should
+// be added at: after every song in the same or a higher tier. The song being played at the
+// top of the temporary setlist is never jumped.
+func queuePosition(sl *Setlist, priority int) int {
+	first := 0
+	if sl.Name == tempSetlistName {
+		first = 1
+	}
+
+	for i := len(sl.Songs); i > first; i-- {
+		if sl.Songs[i-1].Priority >= priority {
+			return i + 1
+		}
+	}
+
+	return min(first, len(sl.Songs)) + 1
+}
+
+// overTarget returns the number of seconds adding the song would put the setlist over its
+// target length, or zero if it wouldn't or the setlist has no target.
+func overTarget(sl *Setlist, s *Song, gap int) int {
+	if sl == nil || sl.Target == 0 {
+		return 0
+	}
+
+	total, _ := sl.estimate(gap)
+	if len(sl.Songs) > 0 {
+		total += gap
+	}
+
+	return max(total+s.Duration-sl.Target, 0)
+}
+
+// removeSong removes a song from a setlist either by its name or its position in the
+// song list. If no name is provided, the song will be removed from the temporary
+// setlist.
+func removeSong(ctx context.Context, db songer, name, song []byte, position int) error {
+	if len(song) == 0 && position < 1 {
+		return errMissingSong
+	}
+
+	if err := db.remove(ctx, setlistName(name), string(song), position); err != nil {
+		return fmt.Errorf("removing song: %w", err)
+	}
+
 	return nil
 }
 
-func removeSong(ctx context.Context, db songer, name []byte) error {
+// replace sets a persisted setlist's songs to the provided songs, creating the setlist if
+// it doesn't exist yet.
+func replace(ctx context.Context, db replacer, name string, songs []*Song) error {
+	sl, err := db.find(ctx, name)
+	if err != nil {
+		return fmt.Errorf("looking up setlist: %w", err)
+	}
+
+	if sl == nil {
+		if _, err := db.create(ctx, name); err != nil {
+			return fmt.Errorf("creating setlist: %w", err)
+		}
+	} else if err := db.clear(ctx, name); err != nil {
+		return fmt.Errorf("clearing setlist: %w", err)
+	}
+
+	for _, s := range songs {
+		if err := db.add(ctx, name, s); err != nil {
+			return fmt.Errorf("adding song %q by %q: %w", s.Name, s.Artist, err)
+		}
+	}
+
 	return nil
 }
+
+// songKey returns a key identifying a song regardless of case or surrounding whitespace,
+// used when comparing songs with each other.
+func songKey(s *Song) string {
+	return strings.ToLower(strings.TrimSpace(s.Artist)) + "\x00" + strings.ToLower(strings.TrimSpace(s.Name))
+}
+
+// setlistName returns the provided name as a string, falling back to the temporary
+// setlist's name when empty.
+func setlistName(name []byte) string {
+	if len(name) == 0 {
+		return tempSetlistName
+	}
+
+	return string(name)
+}
@@ -161,3 +161,278 @@ func TestSetlist(t *testing.T) {
 		})
 	}
 }
+
+func TestReplace(t *testing.T) {
+	songs := []*Song{
+		{Artist: "Dragonforce", Name: "Through the Fire and Flames"},
+		{Artist: "Sum 41", Name: "Fat Lip"},
+	}
+
+	testCases := []struct {
+		name        string
+		db          func(t *testing.T) *Mockdber
+		expectedErr error
+	}{
+		{
+			name: "creates setlist when not found and adds songs",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "Doomed Fingers").Return(nil, nil)
+				db.On("create", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers"}, nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"}).Return(nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Sum 41", Name: "Fat Lip"}).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name: "clears existing setlist before adding songs",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers"}, nil)
+				db.On("clear", mock.Anything, "Doomed Fingers").Return(nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"}).Return(nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Sum 41", Name: "Fat Lip"}).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name: "db returns error when adding song",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "Doomed Fingers").Return(nil, nil)
+				db.On("create", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers"}, nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"}).
+					Return(fmt.Errorf("setlist too epic"))
+
+				return db
+			},
+			expectedErr: fmt.Errorf(`adding song "Through the Fire and Flames" by "Dragonforce": setlist too epic`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := replace(context.Background(), tc.db(t), "Doomed Fingers", songs)
+
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAddSongToSetlist(t *testing.T) {
+	library := []*Chart{
+		{
+			Song: Song{
+				Artist:   "DragonForce",
+				Name:     "Through the Fire and Flames",
+				Duration: 441,
+			},
+			MD5: "0123456789abcdef0123456789abcdef",
+		},
+	}
+	ttfaf := &Song{Artist: "DragonForce", Name: "Through the Fire and Flames", Duration: 441}
+
+	testCases := []struct {
+		name             string
+		request          *songRequest
+		db               func(t *testing.T) *Mockdber
+		expectedWarnings []string
+		expectedErr      error
+	}{
+		{
+			name:    "fills in song details from library",
+			request: &songRequest{Artist: "dragonforce", Song: "through the fire and flames"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil)
+				db.On("add", mock.Anything, tempSetlistName, ttfaf).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name:    "adds song as provided when not in library",
+			request: &songRequest{Setlist: "Doomed Fingers", Artist: "Sum 41", Song: "Fat Lip"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, "Doomed Fingers").Return(&Setlist{Name: "Doomed Fingers"}, nil)
+				db.On("add", mock.Anything, "Doomed Fingers", &Song{Artist: "Sum 41", Name: "Fat Lip"}).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name:    "warns when song puts setlist over its target",
+			request: &songRequest{Artist: "DragonForce", Song: "Through the Fire and Flames", Gap: 30},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name:   tempSetlistName,
+					Songs:  []*Song{{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}},
+					Target: 600,
+				}, nil)
+				db.On("add", mock.Anything, tempSetlistName, ttfaf).Return(nil)
+
+				return db
+			},
+			expectedWarnings: []string{`adding "Through the Fire and Flames" would put "temp" 51s over its target length`},
+		},
+		{
+			name:    "refuses song that puts setlist over its target",
+			request: &songRequest{Artist: "DragonForce", Song: "Through the Fire and Flames", Gap: 30},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name:         tempSetlistName,
+					Songs:        []*Song{{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}},
+					Target:       600,
+					TargetPolicy: targetPolicyRefuse,
+				}, nil)
+
+				return db
+			},
+			expectedErr: &refusalError{reason: `adding "Through the Fire and Flames" would put "temp" 51s over its target length`},
+		},
+		{
+			name:    "queues priority song behind songs in the same or higher tiers",
+			request: &songRequest{Artist: "Sum 41", Song: "Fat Lip", Priority: 1},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name: tempSetlistName,
+					Songs: []*Song{
+						{Artist: "Slayer", Name: "Raining Blood", Priority: 2},
+						{Artist: "Megadeth", Name: "Hangar 18", Priority: 2},
+						{Artist: "Trivium", Name: "Down From the Sky", Priority: 1},
+						{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+					},
+				}, nil)
+				db.On("add", mock.Anything, tempSetlistName, &Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 1}).Return(nil)
+				db.On("move", mock.Anything, tempSetlistName, 5, 4).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name:    "never queues priority song ahead of the song being played",
+			request: &songRequest{Artist: "Sum 41", Song: "Fat Lip", Priority: 3},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name: tempSetlistName,
+					Songs: []*Song{
+						{Artist: "DragonForce", Name: "Through the Fire and Flames"},
+						{Artist: "Trivium", Name: "Down From the Sky", Priority: 1},
+					},
+				}, nil)
+				db.On("add", mock.Anything, tempSetlistName, &Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 3}).Return(nil)
+				db.On("move", mock.Anything, tempSetlistName, 3, 2).Return(nil)
+
+				return db
+			},
+		},
+		{
+			name:    "refuses blocked song",
+			request: &songRequest{Artist: "DragonForce", Song: "Through the Fire and Flames"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(library, nil)
+				db.On("blocklist", mock.Anything).Return(&Blocklist{MaxDuration: 420}, nil)
+
+				return db
+			},
+			expectedErr: &refusalError{reason: `"Through the Fire and Flames" is 7m21s long, over the 7m0s limit`},
+		},
+		{
+			name:    "errors without a song",
+			request: &songRequest{Artist: "Sum 41"},
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expectedErr: errMissingSong,
+		},
+		{
+			name:    "db returns error when looking up library",
+			request: &songRequest{Artist: "Sum 41", Song: "Fat Lip"},
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("library", mock.Anything).Return(nil, fmt.Errorf("it broke"))
+
+				return db
+			},
+			expectedErr: fmt.Errorf("looking up library: it broke"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, warnings, err := addSong(context.Background(), tc.db(t), tc.request)
+
+			assert.Equal(t, tc.expectedWarnings, warnings)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetlistSummarize(t *testing.T) {
+	remaining := -51
+	sl := &Setlist{
+		Songs: []*Song{
+			{Artist: "Sum 41", Name: "Fat Lip", Duration: 180},
+			{Artist: "Trivium", Name: "Down From the Sky"},
+			{Artist: "DragonForce", Name: "Through the Fire and Flames", Duration: 441},
+		},
+		Target: 630,
+	}
+
+	sl.summarize(30)
+
+	assert.Equal(t, 681, sl.Duration)
+	assert.Equal(t, &remaining, sl.Remaining)
+	assert.Equal(t, 1, sl.UnknownDurations)
+}
+
+// requested matches a song requested for a viewer, ignoring when it was requested.
+func requested(expected *Song) any {
+	return mock.MatchedBy(func(s *Song) bool {
+		if s.RequestedAt == nil {
+			return false
+		}
+		actual := *s
+		actual.RequestedAt = nil
+		return assert.ObjectsAreEqual(expected, &actual)
+	})
+}
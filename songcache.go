@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// errIncompleteEntry is returned for songcache entries without an artist or name. The
+// whole entry is read first, so parsing can carry on with the next one.
+var errIncompleteEntry = errors.New("missing artist or name")
+
+// songCacheTables are the string tables at the start of a songcache, in order. Each
+// song entry refers to its metadata by index into these tables.
+var songCacheTables = []string{"name", "artist", "album", "genre", "year", "charter", "playlist"}
+
+// songCacheInstruments are the instruments a songcache entry stores difficulties for, in
+// order.
+var songCacheInstruments = []string{
+	"guitar", "guitar_coop", "rhythm", "bass", "drums", "keys", "vocals", "band",
+}
+
+// parseSongCache reads the charts listed in a Clone Hero songcache file. The file is
+// written with .NET's BinaryWriter (little endian integers, 7-bit length prefixed
+// strings) and is laid out as:
+//
+//   - int32 cache version
+//   - 16 byte checksum of the cache
+//   - a string table for each of songCacheTables: an int32 count followed by its strings
+//   - an int32 entry count followed by the entries
+//
+// Each entry is laid out as:
+//
+//   - the chart file's path as a string
+//   - int64 last write time of the chart file
+//   - an int32 index into each of the string tables
+//   - int32 song length in milliseconds
+//   - a signed byte difficulty for each of songCacheInstruments, -1 when not charted
+//   - 16 byte MD5 checksum of the chart file
+//
+// Only the MD5 is cached, so charts read from a songcache can't be exported to YARG. Entries
+// without an artist or name are logged and skipped, the same as broken charts when walking
+// song directories.
+func parseSongCache(r io.Reader) ([]*Chart, error) {
+	sc := &songCacheReader{r: bufio.NewReader(r)}
+
+	sc.int32() // version
+	sc.skip(16)
+
+	tables := make([][]string, len(songCacheTables))
+	for i := range tables {
+		n := sc.int32()
+		if sc.err != nil {
+			return nil, fmt.Errorf("reading %s table: %w", songCacheTables[i], sc.err)
+		}
+
+		for j := int32(0); j < n && sc.err == nil; j++ {
+			tables[i] = append(tables[i], sc.string())
+		}
+	}
+
+	n := sc.int32()
+	if sc.err != nil {
+		return nil, fmt.Errorf("reading entry count: %w", sc.err)
+	}
+
+	var charts []*Chart
+	for i := int32(0); i < n; i++ {
+		c, err := sc.entry(tables)
+		if errors.Is(err, errIncompleteEntry) {
+			log.Printf("skipping songcache entry %d: %s", i, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading entry %d: %w", i, err)
+		}
+		charts = append(charts, c)
+	}
+
+	return charts, nil
+}
+
+// maxSongCacheString limits the length of strings read from a songcache so a corrupt
+// length can't cause a huge allocation.
+const maxSongCacheString = 1 << 16
+
+// songCacheReader reads values from a songcache, holding on to the first error hit so
+// reads can be chained and checked once.
+type songCacheReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (sc *songCacheReader) read(v any) {
+	if sc.err == nil {
+		sc.err = binary.Read(sc.r, binary.LittleEndian, v)
+	}
+}
+
+func (sc *songCacheReader) int32() int32 {
+	var v int32
+	sc.read(&v)
+	if sc.err == nil && v < 0 {
+		sc.err = fmt.Errorf("invalid count or index %d", v)
+	}
+	return v
+}
+
+func (sc *songCacheReader) skip(n int) {
+	if sc.err == nil {
+		_, sc.err = sc.r.Discard(n)
+	}
+}
+
+func (sc *songCacheReader) string() string {
+	if sc.err != nil {
+		return ""
+	}
+
+	var n uint64
+	if n, sc.err = binary.ReadUvarint(sc.r); sc.err != nil {
+		return ""
+	}
+	if n > maxSongCacheString {
+		sc.err = fmt.Errorf("string length %d too long", n)
+		return ""
+	}
+
+	b := make([]byte, n)
+	_, sc.err = io.ReadFull(sc.r, b)
+	return string(b)
+}
+
+// entry reads a single song entry, resolving its metadata from the string tables.
+func (sc *songCacheReader) entry(tables [][]string) (*Chart, error) {
+	path := sc.string()
+	sc.skip(8) // last write time
+
+	values := make([]string, len(tables))
+	for i := range tables {
+		idx := sc.int32()
+		if sc.err != nil {
+			break
+		}
+		if int(idx) >= len(tables[i]) {
+			return nil, fmt.Errorf("%s index %d out of range", songCacheTables[i], idx)
+		}
+		values[i] = tables[i][idx]
+	}
+
+	var length int32
+	sc.read(&length)
+
+	difficulties := make([]int8, len(songCacheInstruments))
+	sc.read(difficulties)
+
+	var sum [16]byte
+	sc.read(&sum)
+
+	if sc.err != nil {
+		return nil, sc.err
+	}
+
+	c := &Chart{
+		Song: Song{
+			Name:     values[0],
+			Artist:   values[1],
+			Album:    values[2],
+			Genre:    values[3],
+			Charter:  values[5],
+			Duration: int(length / 1000),
+		},
+		MD5: hex.EncodeToString(sum[:]),
+	}
+	c.Year, _ = strconv.Atoi(strings.TrimSpace(strings.TrimLeft(values[4], ", ")))
+	for i, d := range difficulties {
+		if d < 0 {
+			continue
+		}
+		if c.Difficulties == nil {
+			c.Difficulties = map[string]int{}
+		}
+		c.Difficulties[songCacheInstruments[i]] = int(d)
+	}
+
+	if c.Artist == "" || c.Name == "" {
+		return nil, fmt.Errorf("%q: %w", path, errIncompleteEntry)
+	}
+
+	return c, nil
+}
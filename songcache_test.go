@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSongCache(t *testing.T) {
+	sum := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+
+	testCases := []struct {
+		name        string
+		cache       func() []byte
+		expected    []*Chart
+		expectedErr string
+	}{
+		{
+			name: "resolves entries from string tables",
+			cache: func() []byte {
+				var w songCacheWriter
+				w.header([][]string{
+					{"Through the Fire and Flames", "Fat Lip"},
+					{"DragonForce", "Sum 41"},
+					{"Inhuman Rampage"},
+					{"Power Metal"},
+					{", 2006"},
+					{"Harmonix"},
+					{""},
+				})
+				w.int32(2)
+				w.entry("songs/ttfaf/notes.chart", [7]int32{0, 0, 0, 0, 0, 0, 0}, 441000, [8]int8{6, -1, -1, 5, 5, -1, -1, -1}, sum)
+				w.entry("songs/fatlip/notes.mid", [7]int32{1, 1, 0, 0, 0, 0, 0}, 180500, [8]int8{2, -1, -1, -1, -1, -1, -1, -1}, sum)
+				return w.Bytes()
+			},
+			expected: []*Chart{
+				{
+					Song: Song{
+						Artist:       "DragonForce",
+						Name:         "Through the Fire and Flames",
+						Album:        "Inhuman Rampage",
+						Genre:        "Power Metal",
+						Year:         2006,
+						Charter:      "Harmonix",
+						Duration:     441,
+						Difficulties: map[string]int{"guitar": 6, "bass": 5, "drums": 5},
+					},
+					MD5: "0123456789abcdef0123456789abcdef",
+				},
+				{
+					Song: Song{
+						Artist:       "Sum 41",
+						Name:         "Fat Lip",
+						Album:        "Inhuman Rampage",
+						Genre:        "Power Metal",
+						Year:         2006,
+						Charter:      "Harmonix",
+						Duration:     180,
+						Difficulties: map[string]int{"guitar": 2},
+					},
+					MD5: "0123456789abcdef0123456789abcdef",
+				},
+			},
+		},
+		{
+			name: "skips entries without artist or name",
+			cache: func() []byte {
+				var w songCacheWriter
+				w.header([][]string{{"Fat Lip", ""}, {"Sum 41", ""}, {""}, {""}, {""}, {""}, {""}})
+				w.int32(2)
+				w.entry("untitled/notes.chart", [7]int32{1, 1, 0, 0, 0, 0, 0}, 0, [8]int8{-1, -1, -1, -1, -1, -1, -1, -1}, sum)
+				w.entry("fatlip/notes.mid", [7]int32{0, 0, 0, 0, 0, 0, 0}, 180500, [8]int8{2, -1, -1, -1, -1, -1, -1, -1}, sum)
+				return w.Bytes()
+			},
+			expected: []*Chart{
+				{
+					Song: Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180, Difficulties: map[string]int{"guitar": 2}},
+					MD5:  "0123456789abcdef0123456789abcdef",
+				},
+			},
+		},
+		{
+			name: "errors when index is out of range",
+			cache: func() []byte {
+				var w songCacheWriter
+				w.header([][]string{{"Fat Lip"}, {"Sum 41"}, {""}, {""}, {""}, {""}, {""}})
+				w.int32(1)
+				w.entry("notes.mid", [7]int32{0, 3, 0, 0, 0, 0, 0}, 0, [8]int8{}, sum)
+				return w.Bytes()
+			},
+			expectedErr: "reading entry 0: artist index 3 out of range",
+		},
+		{
+			name: "errors when truncated",
+			cache: func() []byte {
+				var w songCacheWriter
+				w.header([][]string{{"Fat Lip"}, {"Sum 41"}, {""}, {""}, {""}, {""}, {""}})
+				w.int32(1)
+				return w.Bytes()
+			},
+			expectedErr: "reading entry 0: EOF",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseSongCache(bytes.NewReader(tc.cache()))
+
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+// songCacheWriter builds songcache data for tests.
+type songCacheWriter struct {
+	bytes.Buffer
+}
+
+func (w *songCacheWriter) int32(v int32) {
+	binary.Write(w, binary.LittleEndian, v) // nolint: errcheck
+}
+
+func (w *songCacheWriter) string(s string) {
+	w.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	w.WriteString(s)
+}
+
+func (w *songCacheWriter) header(tables [][]string) {
+	w.int32(20211215)
+	w.Write(make([]byte, 16))
+	for _, t := range tables {
+		w.int32(int32(len(t)))
+		for _, s := range t {
+			w.string(s)
+		}
+	}
+}
+
+func (w *songCacheWriter) entry(path string, indexes [7]int32, length int32, difficulties [8]int8, sum [16]byte) {
+	w.string(path)
+	w.Write(make([]byte, 8))
+	for _, i := range indexes {
+		w.int32(i)
+	}
+	w.int32(length)
+	binary.Write(w, binary.LittleEndian, difficulties) // nolint: errcheck
+	w.Write(sum[:])
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// chartFiles are the chart file names looked for alongside a song.ini, in order of
+// preference.
+var chartFiles = []string{"notes.chart", "notes.mid"}
+
+// errNoChartFile is returned when a song directory has a song.ini but no chart file.
+var errNoChartFile = errors.New("no chart file found")
+
+// loadChartDir builds a chart from the song.ini in the provided directory and hashes the
+// directory's chart file.
+func loadChartDir(dir string) (*Chart, error) {
+	f, err := os.Open(filepath.Join(dir, "song.ini"))
+	if err != nil {
+		return nil, fmt.Errorf("opening song.ini: %w", err)
+	}
+	defer f.Close()
+
+	c, err := parseSongINI(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing song.ini: %w", err)
+	}
+
+	for _, name := range chartFiles {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+
+		md5Sum, sha1Sum := md5.Sum(b), sha1.Sum(b)
+		c.MD5, c.SHA1 = hex.EncodeToString(md5Sum[:]), hex.EncodeToString(sha1Sum[:])
+		return c, nil
+	}
+
+	return nil, errNoChartFile
+}
+
+// parseSongINI reads a chart's metadata from a song.ini. Keys outside of the [song]
+// section are ignored, as are difficulties of -1 which mark an instrument as not charted.
+func parseSongINI(r io.Reader) (*Chart, error) {
+	c := &Chart{}
+	inSong := false
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inSong = strings.EqualFold(strings.Trim(line, "[]"), "song")
+			continue
+		}
+		if !inSong {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch {
+		case key == "artist":
+			c.Artist = value
+		case key == "name":
+			c.Name = value
+		case key == "album":
+			c.Album = value
+		case key == "genre":
+			c.Genre = value
+		case key == "year":
+			// older charts commonly write the year as ", 2007"
+			c.Year, _ = strconv.Atoi(strings.TrimSpace(strings.TrimLeft(value, ", ")))
+		case key == "charter", key == "frets" && c.Charter == "":
+			c.Charter = value
+		case key == "icon":
+			// charts identify the game or pack they come from by its icon name
+			c.Source = value
+		case key == "song_length":
+			if ms, err := strconv.Atoi(value); err == nil {
+				c.Duration = ms / 1000
+			}
+		case strings.HasPrefix(key, "diff_"):
+			d, err := strconv.Atoi(value)
+			if err != nil || d < 0 {
+				continue
+			}
+			if c.Difficulties == nil {
+				c.Difficulties = map[string]int{}
+			}
+			c.Difficulties[strings.TrimPrefix(key, "diff_")] = d
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.Artist == "" || c.Name == "" {
+		return nil, errors.New("missing artist or name")
+	}
+
+	return c, nil
+}
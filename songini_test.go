@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSongINI(t *testing.T) {
+	testCases := []struct {
+		name        string
+		ini         string
+		expected    *Chart
+		expectedErr string
+	}{
+		{
+			name: "parses song section metadata",
+			ini: "\ufeff[Song]\n" +
+				"name = Through the Fire and Flames\n" +
+				"artist = DragonForce\n" +
+				"album = Inhuman Rampage\n" +
+				"genre = Power Metal\n" +
+				"year = , 2006\n" +
+				"frets = Harmonix\n" +
+				"song_length = 441000\n" +
+				"diff_guitar = 6\n" +
+				"diff_drums = 5\n" +
+				"diff_keys = -1\n" +
+				"; loading_phrase = ignored\n",
+			expected: &Chart{
+				Song: Song{
+					Artist:       "DragonForce",
+					Name:         "Through the Fire and Flames",
+					Album:        "Inhuman Rampage",
+					Genre:        "Power Metal",
+					Year:         2006,
+					Charter:      "Harmonix",
+					Duration:     441,
+					Difficulties: map[string]int{"guitar": 6, "drums": 5},
+				},
+			},
+		},
+		{
+			name: "prefers charter over frets and ignores other sections",
+			ini: "[song]\n" +
+				"charter = Doomed Fingers\n" +
+				"frets = Someone Else\n" +
+				"name = Fat Lip\n" +
+				"artist = Sum 41\n" +
+				"[other]\n" +
+				"artist = Not Sum 41\n",
+			expected: &Chart{
+				Song: Song{
+					Artist:  "Sum 41",
+					Name:    "Fat Lip",
+					Charter: "Doomed Fingers",
+				},
+			},
+		},
+		{
+			name:        "errors when artist or name is missing",
+			ini:         "[song]\nname = Untitled\n",
+			expectedErr: "missing artist or name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseSongINI(strings.NewReader(tc.ini))
+
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestLoadChartDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "song.ini"), []byte("[song]\nname=Fat Lip\nartist=Sum 41\n"), 0o644))
+
+	_, err := loadChartDir(dir)
+	require.ErrorIs(t, err, errNoChartFile)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.mid"), []byte("chart"), 0o644))
+
+	c, err := loadChartDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "b50951613bcd649dc2f9fe580866fe38", c.MD5)
+	assert.Equal(t, "b82b40b84a2f06957ec9caf1768340bd6b929cc4", c.SHA1)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultStatsLimit is the number of entries in each of the stats' top lists when no
+// limit is requested.
+const defaultStatsLimit = 10
+
+// Stats summarizes a streamer's played history.
+type Stats struct {
+	// Plays is the number of songs played.
+	Plays int `json:"plays"`
+	// Streams is the number of streams songs were played on.
+	Streams       int          `json:"streams"`
+	TopSongs      []*SongCount `json:"top_songs"`
+	TopArtists    []*NameCount `json:"top_artists"`
+	TopRequesters []*NameCount `json:"top_requesters"`
+	// RequestsPerStream is the average number of viewers' requests played on each stream.
+	RequestsPerStream float64 `json:"requests_per_stream"`
+	// AverageWait is the average number of seconds viewers' requests waited in the queue
+	// before being played.
+	AverageWait int `json:"average_wait"`
+	// NeverPlayed are songs in the streamer's library that weren't played, up to the same
+	// limit as the top lists.
+	NeverPlayed []*Song `json:"never_played"`
+}
+
+// SongCount is the number of times a song was played.
+type SongCount struct {
+	Song  *Song `json:"song"`
+	Count int   `json:"count"`
+}
+
+// NameCount is the number of times an artist was played or a viewer's requests were.
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// stats summarizes the plays, listing up to limit entries in each top list and of the
+// library's songs that were never played. Ties are broken by whichever was played first.
+func stats(plays []*Play, library []*Chart, limit int) *Stats {
+	plays = slices.Clone(plays)
+	slices.SortStableFunc(plays, func(a, b *Play) int { return a.PlayedAt.Compare(b.PlayedAt) })
+
+	st := &Stats{Plays: len(plays)}
+	songs := &counter[*Song]{key: songKey}
+	artists := &counter[string]{key: func(a string) string { return strings.ToLower(strings.TrimSpace(a)) }}
+	requesters := &counter[string]{key: func(r string) string { return strings.ToLower(r) }}
+	streams := map[string]bool{}
+	var (
+		requests int
+		waited   time.Duration
+		waits    int
+	)
+	for _, p := range plays {
+		streams[p.Stream] = true
+		songs.add(p.Song)
+		artists.add(p.Song.Artist)
+
+		if p.Song.RequestedBy == "" {
+			continue
+		}
+		requests++
+		requesters.add(p.Song.RequestedBy)
+		if p.Song.RequestedAt != nil && p.PlayedAt.After(*p.Song.RequestedAt) {
+			waited += p.PlayedAt.Sub(*p.Song.RequestedAt)
+			waits++
+		}
+	}
+
+	st.Streams = len(streams)
+	st.TopSongs = []*SongCount{}
+	for _, c := range songs.top(limit) {
+		st.TopSongs = append(st.TopSongs, &SongCount{Song: c.value, Count: c.count})
+	}
+	st.TopArtists = names(artists.top(limit))
+	st.TopRequesters = names(requesters.top(limit))
+	if st.Streams > 0 {
+		st.RequestsPerStream = float64(requests) / float64(st.Streams)
+	}
+	if waits > 0 {
+		st.AverageWait = int((waited / time.Duration(waits)).Seconds())
+	}
+
+	st.NeverPlayed = []*Song{}
+	for _, c := range library {
+		if len(st.NeverPlayed) == limit {
+			break
+		}
+		if !songs.has(&c.Song) {
+			st.NeverPlayed = append(st.NeverPlayed, &c.Song)
+		}
+	}
+
+	return st
+}
+
+// counter counts occurrences of values, treating values with the same key as the same.
+// The first value seen for each key is the one kept.
+type counter[T any] struct {
+	key    func(T) string
+	counts map[string]*count[T]
+	// order holds the keys in the order they were first seen.
+	order []string
+}
+
+type count[T any] struct {
+	value T
+	count int
+}
+
+func (c *counter[T]) add(v T) {
+	if c.counts == nil {
+		c.counts = map[string]*count[T]{}
+	}
+
+	k := c.key(v)
+	if n, ok := c.counts[k]; ok {
+		n.count++
+		return
+	}
+	c.counts[k] = &count[T]{value: v, count: 1}
+	c.order = append(c.order, k)
+}
+
+func (c *counter[T]) has(v T) bool {
+	_, ok := c.counts[c.key(v)]
+	return ok
+}
+
+// top returns up to limit of the most counted values, most counted first.
+func (c *counter[T]) top(limit int) []*count[T] {
+	counts := make([]*count[T], 0, len(c.order))
+	for _, k := range c.order {
+		counts = append(counts, c.counts[k])
+	}
+	slices.SortStableFunc(counts, func(a, b *count[T]) int { return cmp.Compare(b.count, a.count) })
+
+	return counts[:min(len(counts), limit)]
+}
+
+// names converts counted names to NameCounts, always returning a non-nil slice.
+func names(counts []*count[string]) []*NameCount {
+	n := make([]*NameCount, 0, len(counts))
+	for _, c := range counts {
+		n = append(n, &NameCount{Name: c.value, Count: c.count})
+	}
+
+	return n
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	start := time.Date(2024, 3, 9, 19, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	fatLip := Song{Artist: "Sum 41", Name: "Fat Lip"}
+	inTooDeep := Song{Artist: "Sum 41", Name: "In Too Deep"}
+	downFromTheSky := Song{Artist: "Trivium", Name: "Down From the Sky"}
+	library := []*Chart{{Song: fatLip}, {Song: inTooDeep}, {Song: downFromTheSky}}
+
+	plays := []*Play{
+		{Song: &Song{Artist: "Sum 41", Name: "Fat Lip", RequestedBy: "cooler_user", RequestedAt: at(0)}, Stream: "a", PlayedAt: *at(10)},
+		{Song: &Song{Artist: "Trivium", Name: "Down From the Sky"}, Stream: "a", PlayedAt: *at(15)},
+		{Song: &Song{Artist: "sum 41", Name: "fat lip", RequestedBy: "Cooler_User", RequestedAt: at(1440)}, Stream: "b", PlayedAt: *at(1460)},
+		{Song: &Song{Artist: "Trivium", Name: "Down From the Sky", RequestedBy: "other_user"}, Stream: "b", PlayedAt: *at(1465)},
+		{Song: &Song{Artist: "Sum 41", Name: "Fat Lip"}, Stream: "b", PlayedAt: *at(1470)},
+	}
+
+	st := stats(plays, library, 10)
+	assert.Equal(t, &Stats{
+		Plays:   5,
+		Streams: 2,
+		TopSongs: []*SongCount{
+			{Song: plays[0].Song, Count: 3},
+			{Song: plays[1].Song, Count: 2},
+		},
+		TopArtists:        []*NameCount{{Name: "Sum 41", Count: 3}, {Name: "Trivium", Count: 2}},
+		TopRequesters:     []*NameCount{{Name: "cooler_user", Count: 2}, {Name: "other_user", Count: 1}},
+		RequestsPerStream: 1.5,
+		AverageWait:       900,
+		NeverPlayed:       []*Song{&library[1].Song},
+	}, st)
+
+	limited := stats(plays, library, 1)
+	assert.Len(t, limited.TopSongs, 1)
+	assert.Len(t, limited.TopArtists, 1)
+	assert.Len(t, limited.TopRequesters, 1)
+
+	unplayed := stats(nil, library, 2)
+	assert.Equal(t, []*Song{&library[0].Song, &library[1].Song}, unplayed.NeverPlayed)
+
+	empty := stats(nil, nil, 10)
+	assert.Equal(t, &Stats{
+		TopSongs:      []*SongCount{},
+		TopArtists:    []*NameCount{},
+		TopRequesters: []*NameCount{},
+		NeverPlayed:   []*Song{},
+	}, empty)
+}
+
+func TestGetStats(t *testing.T) {
+	testCases := []struct {
+		name               string
+		query              string
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "narrows history to range",
+			query: "?since=2024-03-01&until=2024-03-09&limit=1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("stats", mock.Anything, historyQuery{
+					Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+					Until: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+				}, 1).Return(stats([]*Play{{Song: &Song{Artist: "Sum 41", Name: "Fat Lip"}, Stream: "a"}}, nil, 1), nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"plays":1,"streams":1,` +
+				`"top_songs":[{"song":{"artist":"Sum 41","name":"Fat Lip"},"count":1}],` +
+				`"top_artists":[{"name":"Sum 41","count":1}],"top_requesters":[],` +
+				`"requests_per_stream":0,"average_wait":0,"never_played":[]}`,
+		},
+		{
+			name:  "returns error when summarizing fails",
+			query: "?streams=3",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("stats", mock.Anything, historyQuery{Streams: 3}, defaultStatsLimit).Return(nil, errors.New("something broke"))
+
+				return db
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       `{"error":"failed to get stats"}`,
+		},
+		{
+			name:               "refuses bad dates",
+			query:              "?since=last%20week",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"since must be a date like 2024-03-09 or an RFC 3339 time"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/stats" + tc.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/valyala/fasthttp"
+)
+
+// streamerKey is the context key a request's streamer is stored under.
+type streamerKey struct{}
+
+// withStreamer returns a copy of ctx scoped to the provided streamer.
+func withStreamer(ctx context.Context, streamer string) context.Context {
+	return context.WithValue(ctx, streamerKey{}, streamer)
+}
+
+// streamerFrom returns the streamer ctx is scoped to or an empty string if it isn't scoped
+// to one. The db uses it to determine which streamer's collection to work with.
+func streamerFrom(ctx context.Context) string {
+	s, _ := ctx.Value(streamerKey{}).(string)
+	return s
+}
+
+// scopeStreamer wraps a handler, scoping each request to the streamer named by its
+// streamer query parameter. Since a fasthttp.RequestCtx looks up context values from its
+// user values, contexts derived from the request carry the streamer along with them.
+func scopeStreamer(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(rctx *fasthttp.RequestCtx) {
+		rctx.SetUserValue(streamerKey{}, string(rctx.QueryArgs().Peek("streamer")))
+		h(rctx)
+	}
+}
+
+// mod reports whether the request was made on behalf of the streamer or one of their mods,
+// which bots show by sending the server's mod token as the mod_token query parameter. No
+// request is a mod's when the server isn't configured with a token.
+func (s *server) mod(rctx *fasthttp.RequestCtx) bool {
+	token := rctx.QueryArgs().Peek("mod_token")
+	return len(s.modToken) > 0 && subtle.ConstantTimeCompare(token, s.modToken) == 1
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestScopeStreamer(t *testing.T) {
+	var actual string
+	h := scopeStreamer(func(rctx *fasthttp.RequestCtx) {
+		ctx, cancel := context.WithTimeout(rctx, 5*time.Second)
+		defer cancel()
+
+		actual = streamerFrom(ctx)
+	})
+	client := newTestServer(t, h)
+
+	resp, err := client.Get(lh + "?streamer=doomedfingers")
+
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "doomedfingers", actual)
+	assert.Equal(t, "", streamerFrom(context.Background()))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// tipSignatureHeader is the header tip events are signed in, holding the hex encoded
+	// HMAC-SHA256 of the event's ID, timestamp and the request body prefixed with "sha256=".
+	tipSignatureHeader = "X-Songvoyage-Signature"
+	// tipIDHeader holds an ID unique to each event, used to handle each event only once.
+	tipIDHeader = "X-Songvoyage-Event-Id"
+	// tipTimestampHeader holds when the event was sent, in RFC 3339 format.
+	tipTimestampHeader = "X-Songvoyage-Timestamp"
+
+	// tipMaxAge is how old a tip event can be before it's refused as a possible replay. It
+	// matches how long seenMessages remembers event IDs for deduping.
+	tipMaxAge = eventSubMaxAge
+)
+
+// tipEvent is a tip, bits cheer, channel point redemption or similar sent to the tips
+// webhook by an external service, carrying the song the viewer requested.
+type tipEvent struct {
+	Streamer string `json:"streamer"`
+	// Kind is the kind of event, e.g. tip or bits, used to pick the tiers its amount is
+	// compared against.
+	Kind string `json:"kind"`
+	User string `json:"user"`
+	// Amount is the amount in the kind's own unit, e.g. cents for tips.
+	Amount int    `json:"amount"`
+	Artist string `json:"artist"`
+	Song   string `json:"song"`
+}
+
+// tipTier is the priority tier songs are requested in when an event's amount is at least
+// Min.
+type tipTier struct {
+	Min  int
+	Tier int
+}
+
+// tipTiers holds the tiers for each kind of event, ordered by their minimum amounts.
+type tipTiers map[string][]tipTier
+
+// parseTipTiers parses tiers in the form used by the -tip-tiers flag: kinds separated by
+// semicolons, each with comma separated amount=tier pairs, e.g.
+// "tip:500=1,2000=2;bits:100=1".
+func parseTipTiers(s string) (tipTiers, error) {
+	tt := tipTiers{}
+	for _, k := range strings.Split(s, ";") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+
+		kind, pairs, ok := strings.Cut(k, ":")
+		if !ok || kind == "" {
+			return nil, fmt.Errorf("%q must be a kind followed by its tiers, e.g. bits:100=1", k)
+		}
+
+		for _, p := range strings.Split(pairs, ",") {
+			amount, tier, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok {
+				return nil, fmt.Errorf("%q must be an amount and tier, e.g. 100=1", p)
+			}
+
+			var t tipTier
+			var err error
+			if t.Min, err = strconv.Atoi(amount); err != nil {
+				return nil, fmt.Errorf("amount %q must be a number", amount)
+			}
+			if t.Tier, err = strconv.Atoi(tier); err != nil || t.Tier < 1 {
+				return nil, fmt.Errorf("tier %q must be a positive number", tier)
+			}
+			tt[kind] = append(tt[kind], t)
+		}
+
+		slices.SortFunc(tt[kind], func(a, b tipTier) int { return a.Min - b.Min })
+	}
+
+	return tt, nil
+}
+
+// tier returns the priority tier for an event of the provided kind and amount. Events
+// under the lowest tier's minimum, or of kinds without tiers, are in tier zero.
+func (tt tipTiers) tier(kind string, amount int) int {
+	tier := 0
+	for _, t := range tt[kind] {
+		if amount < t.Min {
+			break
+		}
+		tier = t.Tier
+	}
+
+	return tier
+}
+
+// signTip returns the signature for a tip event, as sent in tipSignatureHeader.
+func signTip(secret []byte, id, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyTip reports whether the signature matches the event.
+func verifyTip(secret []byte, id, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(signTip(secret, id, timestamp, body)))
+}
+
+// sendTip signs and sends a tip event to the server's tips webhook. It stands in for an
+// external service when trying out tiers locally and in tests.
+func sendTip(client *http.Client, server string, secret []byte, e *tipEvent) error {
+	req, err := tipRequest(server, secret, primitive.NewObjectID().Hex(), time.Now(), e)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+// tipRequest returns a request sending the tip event to the server's tips webhook with the
+// provided ID, signed as sent at the provided time.
+func tipRequest(server string, secret []byte, id string, sent time.Time, e *tipEvent) (*http.Request, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server+"/webhooks/tips", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := sent.UTC().Format(time.RFC3339Nano)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(tipIDHeader, id)
+	req.Header.Set(tipTimestampHeader, timestamp)
+	req.Header.Set(tipSignatureHeader, signTip(secret, id, timestamp, b))
+
+	return req, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTipTiers(t *testing.T) {
+	tt, err := parseTipTiers("tip: 2000=2, 500=1; bits:100=1")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		kind     string
+		amount   int
+		expected int
+	}{
+		{kind: "tip", amount: 499, expected: 0},
+		{kind: "tip", amount: 500, expected: 1},
+		{kind: "tip", amount: 2500, expected: 2},
+		{kind: "bits", amount: 100, expected: 1},
+		{kind: "points", amount: 100000, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %d", tc.kind, tc.amount), func(t *testing.T) {
+			assert.Equal(t, tc.expected, tt.tier(tc.kind, tc.amount))
+		})
+	}
+
+	for _, bad := range []string{"tip", "tip:500", "tip:lots=1", "tip:500=0"} {
+		_, err := parseTipTiers(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestTipWebhook(t *testing.T) {
+	secret := []byte("hunter2")
+	event := &tipEvent{Streamer: "mxygem", Kind: "bits", User: "viewer", Amount: 500, Artist: "Sum 41", Song: "Fat Lip"}
+
+	testCases := []struct {
+		name        string
+		secret      []byte
+		sendSecret  []byte
+		db          func(t *testing.T) *Mockdber
+		expectedErr error
+	}{
+		{
+			name:       "requests song in the event's tier",
+			secret:     secret,
+			sendSecret: secret,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				song := &Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 2, RequestedBy: "viewer"}
+				db.On("reviewing", mock.Anything).Return(false, nil)
+				db.On("library", mock.Anything).Return([]*Chart{}, nil)
+				db.On("blocklist", mock.Anything).Return(nil, nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+				db.On("add", mock.MatchedBy(func(ctx context.Context) bool {
+					return streamerFrom(ctx) == "mxygem"
+				}), tempSetlistName, requested(song)).Return(nil)
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name:  tempSetlistName,
+					Songs: []*Song{song},
+				}, nil)
+
+				return db
+			},
+		},
+		{
+			name:       "refuses events with a bad signature",
+			secret:     secret,
+			sendSecret: []byte("hunter3"),
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expectedErr: fmt.Errorf(`unexpected status 401: {"error":"invalid signature"}`),
+		},
+		{
+			name:       "refuses events when no secret is configured",
+			sendSecret: secret,
+			db: func(t *testing.T) *Mockdber {
+				return NewMockdber(t)
+			},
+			expectedErr: fmt.Errorf(`unexpected status 404: {"error":"tips aren't enabled"}`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			s.tipSecret = tc.secret
+			s.tipTiers = tipTiers{"bits": {{Min: 100, Tier: 1}, {Min: 500, Tier: 2}}}
+			client := newTestServer(t, routes(s).Handler)
+
+			err := sendTip(client, lh, tc.sendSecret, event)
+
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTipWebhookReplay(t *testing.T) {
+	secret := []byte("hunter2")
+	event := &tipEvent{Streamer: "mxygem", Kind: "bits", User: "viewer", Amount: 500, Artist: "Sum 41", Song: "Fat Lip"}
+	song := &Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 2, RequestedBy: "viewer"}
+
+	db := NewMockdber(t)
+	db.On("reviewing", mock.Anything).Return(false, nil).Once()
+	db.On("library", mock.Anything).Return([]*Chart{}, nil).Once()
+	db.On("blocklist", mock.Anything).Return(nil, nil).Once()
+	db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+	db.On("add", mock.Anything, tempSetlistName, requested(song)).Return(nil).Once()
+	db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{Name: tempSetlistName, Songs: []*Song{song}}, nil).Once()
+
+	s := newServer()
+	s.db = db
+	s.tipSecret = secret
+	s.tipTiers = tipTiers{"bits": {{Min: 100, Tier: 1}, {Min: 500, Tier: 2}}}
+	client := newTestServer(t, routes(s).Handler)
+
+	send := func(id string, sent time.Time) (int, string) {
+		req, err := tipRequest(lh, secret, id, sent, event)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	sent := time.Now()
+	status, _ := send("65f0c0ffee", sent)
+	assert.Equal(t, http.StatusOK, status)
+
+	// the same event sent again is acknowledged without requesting the song again
+	status, body := send("65f0c0ffee", sent)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Empty(t, body)
+
+	status, body = send("65f0decade", sent.Add(-tipMaxAge-time.Minute))
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, `{"error":"event is too old"}`, body)
+
+	status, body = send("", sent)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, `{"error":"an event ID is required"}`, body)
+}
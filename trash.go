@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// defaultTrashRetention is how long deleted setlists are kept in the trash when not
+	// configured.
+	defaultTrashRetention = 30 * 24 * time.Hour
+	// trashPurgeInterval is how often expired setlists are purged from the trash.
+	trashPurgeInterval = time.Hour
+)
+
+var (
+	// errTrashedNotFound is returned when restoring a setlist that isn't in the trash,
+	// including one that expired.
+	errTrashedNotFound = errors.New("setlist not found in trash")
+	// errSetlistExists is returned when restoring a setlist under the name of one that
+	// already exists.
+	errSetlistExists = errors.New("a setlist with that name already exists")
+)
+
+// TrashedSetlist is a deleted setlist kept in the trash until it expires. Setlists in the
+// trash are identified by their own ID rather than their name, since a setlist's name is
+// free to be used again once it's deleted.
+type TrashedSetlist struct {
+	ID        string    `json:"id"`
+	Setlist   *Setlist  `json:"setlist"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// restoreTrashed moves the setlist with the provided trash ID back to the streamer's
+// setlists. It's restored under its own name unless another name is provided, failing
+// with errSetlistExists if a setlist with that name was created since it was deleted.
+func restoreTrashed(ctx context.Context, db trashRestorer, id, name string) (*Setlist, error) {
+	trashed, err := db.trashed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("looking up trash: %w", err)
+	}
+
+	var t *TrashedSetlist
+	for _, tr := range trashed {
+		if tr.ID == id {
+			t = tr
+			break
+		}
+	}
+	if t == nil {
+		return nil, errTrashedNotFound
+	}
+
+	if name == "" {
+		name = t.Setlist.Name
+	}
+	existing, err := db.find(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("looking up setlist: %w", err)
+	}
+	if existing != nil {
+		return nil, errSetlistExists
+	}
+
+	sl, err := db.untrash(ctx, id, name)
+	if err != nil {
+		return nil, fmt.Errorf("restoring setlist: %w", err)
+	}
+	if sl == nil {
+		return nil, errTrashedNotFound
+	}
+
+	return sl, nil
+}
+
+// purgeTrash purges expired setlists from the trash every interval until ctx is done.
+// Failures are logged and retried on the next tick.
+func purgeTrash(ctx context.Context, db trasher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := db.purge(ctx, now.UTC()); err != nil {
+				log.Printf("purge trash - purging expired setlists: %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreTrashed(t *testing.T) {
+	trashed := []*TrashedSetlist{
+		{ID: "2", Setlist: &Setlist{Name: "chill"}},
+		{ID: "1", Setlist: &Setlist{Name: "chill"}},
+	}
+
+	testCases := []struct {
+		name         string
+		id           string
+		restoreAs    string
+		db           func(t *testing.T) *Mockdber
+		expectedName string
+		expectedErr  error
+	}{
+		{
+			name: "restores under own name",
+			id:   "1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("trashed", mock.Anything).Return(trashed, nil)
+				db.On("find", mock.Anything, "chill").Return(nil, nil)
+				db.On("untrash", mock.Anything, "1", "chill").Return(&Setlist{Name: "chill"}, nil)
+
+				return db
+			},
+			expectedName: "chill",
+		},
+		{
+			name: "refuses name taken since deleting",
+			id:   "2",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("trashed", mock.Anything).Return(trashed, nil)
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill"}, nil)
+
+				return db
+			},
+			expectedErr: errSetlistExists,
+		},
+		{
+			name:      "restores under another name",
+			id:        "2",
+			restoreAs: "chill (old)",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("trashed", mock.Anything).Return(trashed, nil)
+				db.On("find", mock.Anything, "chill (old)").Return(nil, nil)
+				db.On("untrash", mock.Anything, "2", "chill (old)").Return(&Setlist{Name: "chill (old)"}, nil)
+
+				return db
+			},
+			expectedName: "chill (old)",
+		},
+		{
+			name: "errors when not in trash",
+			id:   "3",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("trashed", mock.Anything).Return(trashed, nil)
+
+				return db
+			},
+			expectedErr: errTrashedNotFound,
+		},
+		{
+			name: "db returns error when looking up trash",
+			id:   "1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("trashed", mock.Anything).Return(nil, fmt.Errorf("it broke"))
+
+				return db
+			},
+			expectedErr: fmt.Errorf("looking up trash: it broke"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sl, err := restoreTrashed(context.Background(), tc.db(t), tc.id, tc.restoreAs)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedName, sl.Name)
+		})
+	}
+}
+
+func TestDeleteSetlist(t *testing.T) {
+	testCases := []struct {
+		name               string
+		query              string
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "moves setlist to trash",
+			query: "?name=chill",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill"}, nil)
+				db.On("delete", mock.Anything, "chill", mock.MatchedBy(func(expires time.Time) bool {
+					return time.Until(expires) > 29*24*time.Hour
+				})).Return(nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:  "setlist not found",
+			query: "?name=chill",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(nil, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error":"setlist not found"}`,
+		},
+		{
+			name:               "requires name",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"a setlist name is required"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/setlist/delete" + tc.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			if tc.expectedBody != "" {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tc.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	purged := make(chan time.Time, 1)
+	db := NewMockdber(t)
+	db.On("purge", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		select {
+		case purged <- args.Get(1).(time.Time):
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purgeTrash(ctx, db, 10*time.Millisecond)
+	}()
+
+	select {
+	case before := <-purged:
+		assert.WithinDuration(t, time.Now(), before, time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("trash wasn't purged")
+	}
+
+	cancel()
+	<-done
+}
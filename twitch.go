@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// eventSubMaxAge is how old an EventSub message can be before it's refused as a
+	// possible replay. Messages are only remembered for deduping for this long.
+	eventSubMaxAge = 10 * time.Minute
+
+	eventSubVerification = "webhook_callback_verification"
+	eventSubNotification = "notification"
+	eventSubRevocation   = "revocation"
+
+	// eventSubRedemption is the subscription type for channel point custom reward
+	// redemptions.
+	eventSubRedemption = "channel.channel_points_custom_reward_redemption.add"
+)
+
+// eventSubMessage is the body of a Twitch EventSub webhook request. Only the fields used
+// for song requests are included.
+type eventSubMessage struct {
+	// Challenge is sent when verifying the callback and must be echoed back.
+	Challenge    string `json:"challenge"`
+	Subscription struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		Status string `json:"status"`
+	} `json:"subscription"`
+	Event *redemptionEvent `json:"event"`
+}
+
+// redemptionEvent is a viewer redeeming a channel point custom reward.
+type redemptionEvent struct {
+	ID               string `json:"id"`
+	BroadcasterLogin string `json:"broadcaster_user_login"`
+	UserLogin        string `json:"user_login"`
+	// UserInput is the text the viewer entered when redeeming, for song requests the
+	// artist and song separated by a dash.
+	UserInput string `json:"user_input"`
+	Reward    struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+		Cost  int    `json:"cost"`
+	} `json:"reward"`
+}
+
+// signEventSub returns the signature Twitch sends in the Twitch-Eventsub-Message-Signature
+// header: the hex encoded HMAC-SHA256 of the message ID, timestamp and body prefixed with
+// "sha256=".
+func signEventSub(secret []byte, id, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyEventSub reports whether the signature matches the message.
+func verifyEventSub(secret []byte, id, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(signEventSub(secret, id, timestamp, body)))
+}
+
+// parseSongInput splits a viewer's input into an artist and song, e.g. "Sum 41 - Fat Lip".
+// ok is false when the input isn't in that form.
+func parseSongInput(input string) (artist, song string, ok bool) {
+	artist, song, ok = strings.Cut(input, " - ")
+	artist, song = strings.TrimSpace(artist), strings.TrimSpace(song)
+
+	return artist, song, ok && artist != "" && song != ""
+}
+
+// seenMessages remembers the IDs of recently handled messages so that retried deliveries
+// are only handled once.
+type seenMessages struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	// now returns the current time, replaceable in tests.
+	now func() time.Time
+}
+
+func newSeenMessages() *seenMessages {
+	return &seenMessages{seen: map[string]time.Time{}, now: time.Now}
+}
+
+// first records the message ID, reporting whether it's the first time it has been seen.
+// IDs older than eventSubMaxAge are forgotten since messages that old are refused anyway.
+func (sm *seenMessages) first(id string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	now := sm.now()
+	maps.DeleteFunc(sm.seen, func(_ string, at time.Time) bool {
+		return now.Sub(at) > eventSubMaxAge
+	})
+
+	if _, ok := sm.seen[id]; ok {
+		return false
+	}
+	sm.seen[id] = now
+
+	return true
+}
+
+// forget removes the message ID so that a retried delivery is handled again, used when
+// handling the message failed.
+func (sm *seenMessages) forget(id string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	// the builtin delete is shadowed by the package's delete
+	maps.DeleteFunc(sm.seen, func(k string, _ time.Time) bool { return k == id })
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwitchEventSub(t *testing.T) {
+	secret := []byte("s3cr3t-s3cr3t")
+
+	testCases := []struct {
+		name        string
+		payload     string
+		messageType string
+		// signSecret signs the message instead of the server's secret when set.
+		signSecret []byte
+		sentAt     time.Time
+		// deliveries is the number of times the message is delivered, defaulting to once.
+		deliveries         int
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "answers callback verification with its challenge",
+			payload:            "verification.json",
+			messageType:        eventSubVerification,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "pogchamp-kappa-360noscope-vohiyo",
+		},
+		{
+			name:        "requests redeemed song on the broadcaster's temp setlist once",
+			payload:     "redemption.json",
+			messageType: eventSubNotification,
+			deliveries:  2,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				song := &Song{Artist: "Sum 41", Name: "Fat Lip", Priority: 1, RequestedBy: "cooler_user"}
+				db.On("reviewing", mock.Anything).Return(false, nil).Once()
+				db.On("library", mock.Anything).Return([]*Chart{}, nil).Once()
+				db.On("blocklist", mock.Anything).Return(nil, nil).Once()
+				db.On("find", mock.Anything, tempSetlistName).Return(nil, nil).Once()
+				db.On("add", mock.MatchedBy(func(ctx context.Context) bool {
+					return streamerFrom(ctx) == "doomedfingers"
+				}), tempSetlistName, requested(song)).Return(nil).Once()
+				db.On("find", mock.Anything, tempSetlistName).Return(&Setlist{
+					Name:  tempSetlistName,
+					Songs: []*Song{song},
+				}, nil).Once()
+
+				return db
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "ignores redemptions of other rewards with the same title",
+			payload:            "other_reward.json",
+			messageType:        eventSubNotification,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "ignores redemptions without an artist and song",
+			payload:            "bad_input.json",
+			messageType:        eventSubNotification,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "acknowledges revocations",
+			payload:            "revocation.json",
+			messageType:        eventSubRevocation,
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "refuses messages with a bad signature",
+			payload:            "redemption.json",
+			messageType:        eventSubNotification,
+			signSecret:         []byte("not-the-secret"),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       `{"error":"invalid signature"}`,
+		},
+		{
+			name:               "refuses old messages",
+			payload:            "redemption.json",
+			messageType:        eventSubNotification,
+			sentAt:             time.Now().Add(-time.Hour),
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       `{"error":"message is too old"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", "eventsub", tc.payload))
+			require.NoError(t, err)
+
+			s := newServer()
+			s.db = NewMockdber(t)
+			if tc.db != nil {
+				s.db = tc.db(t)
+			}
+			s.twitchSecret = secret
+			s.twitchRewardID = "92af127c-7326-4483-a52b-b0da0be61c01"
+			s.tipTiers = tipTiers{"points": {{Min: 1000, Tier: 1}}}
+			client := newTestServer(t, routes(s).Handler)
+
+			signSecret, sentAt := secret, time.Now()
+			if tc.signSecret != nil {
+				signSecret = tc.signSecret
+			}
+			if !tc.sentAt.IsZero() {
+				sentAt = tc.sentAt
+			}
+			id, timestamp := "e76c6bd4-55c9-4987-8304-da1588d8988b", sentAt.UTC().Format(time.RFC3339Nano)
+
+			var resp *http.Response
+			for i := 0; i < max(tc.deliveries, 1); i++ {
+				req, err := http.NewRequest(http.MethodPost, lh+"/webhooks/twitch/eventsub", bytes.NewReader(body))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Twitch-Eventsub-Message-Id", id)
+				req.Header.Set("Twitch-Eventsub-Message-Timestamp", timestamp)
+				req.Header.Set("Twitch-Eventsub-Message-Type", tc.messageType)
+				req.Header.Set("Twitch-Eventsub-Message-Signature", signEventSub(signSecret, id, timestamp, body))
+
+				resp, err = client.Do(req)
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			respBody, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(respBody))
+		})
+	}
+}
+
+func TestParseSongInput(t *testing.T) {
+	artist, song, ok := parseSongInput("  Sum 41 - Fat Lip ")
+	assert.True(t, ok)
+	assert.Equal(t, "Sum 41", artist)
+	assert.Equal(t, "Fat Lip", song)
+
+	for _, bad := range []string{"Fat Lip", "Sum 41 - ", " - Fat Lip"} {
+		_, _, ok := parseSongInput(bad)
+		assert.False(t, ok, bad)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultVersions is the number of versions kept of each setlist when not configured.
+const defaultVersions = 20
+
+var (
+	// errVersionNotFound is returned when a setlist doesn't have a version with the
+	// requested ID.
+	errVersionNotFound = errors.New("version not found")
+	// errRenamedOver is returned when undoing a rename after a setlist has been created
+	// under the old name.
+	errRenamedOver = errors.New("a setlist with the old name was created since it was renamed")
+)
+
+// Version is what a setlist was before a change was made to it.
+type Version struct {
+	ID string `json:"id"`
+	// Setlist is the name of the setlist after the change, so the version is found under
+	// the setlist's new name when the change renamed it.
+	Setlist string `json:"setlist"`
+	// OldName is the setlist's name before the change when the change renamed it.
+	OldName      string       `json:"old_name,omitempty"`
+	Songs        []*Song      `json:"songs"`
+	Tags         []string     `json:"tags,omitempty"`
+	Target       int          `json:"target,omitempty"`
+	TargetPolicy targetPolicy `json:"target_policy,omitempty"`
+	// Action is the change that was made after the version was recorded, e.g. clear.
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// versioned wraps a dber, recording a version of a setlist before each change made to it
+// through clear, add, remove, move, update, tag, target and delete. Only the most recent
+// keep versions of each setlist are kept and no versions are recorded when keep is zero.
+type versioned struct {
+	dber
+	keep int
+}
+
+func (v *versioned) clear(ctx context.Context, name string) error {
+	if err := v.version(ctx, name, name, "clear"); err != nil {
+		return err
+	}
+
+	return v.dber.clear(ctx, name)
+}
+
+func (v *versioned) add(ctx context.Context, setlistName string, song *Song) error {
+	if err := v.version(ctx, setlistName, setlistName, "add"); err != nil {
+		return err
+	}
+
+	return v.dber.add(ctx, setlistName, song)
+}
+
+func (v *versioned) remove(ctx context.Context, setlistName, songName string, songNumber int) error {
+	if err := v.version(ctx, setlistName, setlistName, "remove"); err != nil {
+		return err
+	}
+
+	return v.dber.remove(ctx, setlistName, songName, songNumber)
+}
+
+func (v *versioned) move(ctx context.Context, setlistName string, from, to int) error {
+	if err := v.version(ctx, setlistName, setlistName, "move"); err != nil {
+		return err
+	}
+
+	return v.dber.move(ctx, setlistName, from, to)
+}
+
+func (v *versioned) update(ctx context.Context, oldName, newName string) (*Setlist, error) {
+	if err := v.version(ctx, oldName, newName, "update"); err != nil {
+		return nil, err
+	}
+
+	return v.dber.update(ctx, oldName, newName)
+}
+
+func (v *versioned) tag(ctx context.Context, name string, tags []string) error {
+	if err := v.version(ctx, name, name, "tag"); err != nil {
+		return err
+	}
+
+	return v.dber.tag(ctx, name, tags)
+}
+
+func (v *versioned) target(ctx context.Context, name string, target int, policy targetPolicy) error {
+	if err := v.version(ctx, name, name, "target"); err != nil {
+		return err
+	}
+
+	return v.dber.target(ctx, name, target, policy)
+}
+
+func (v *versioned) delete(ctx context.Context, name string, expires time.Time) error {
+	if err := v.version(ctx, name, name, "delete"); err != nil {
+		return err
+	}
+
+	return v.dber.delete(ctx, name, expires)
+}
+
+// version snapshots the setlist before a change is made to it, recording it under the
+// name the change leaves it with. When ctx groups changes only the first change to each
+// setlist is recorded, and the group's action is used over the provided one if it has
+// one. Setlists that don't exist yet have nothing to record.
+func (v *versioned) version(ctx context.Context, name, newName, action string) error {
+	if v.keep <= 0 {
+		return nil
+	}
+	if c, ok := ctx.Value(changeKey{}).(*change); ok {
+		if !c.first(newName) {
+			return nil
+		}
+		if c.action != "" {
+			action = c.action
+		}
+	}
+
+	sl, err := v.dber.find(ctx, name)
+	if err != nil {
+		return fmt.Errorf("looking up setlist to version: %w", err)
+	}
+	if sl == nil {
+		return nil
+	}
+
+	version := &Version{
+		ID:           primitive.NewObjectID().Hex(),
+		Setlist:      newName,
+		Songs:        sl.Songs,
+		Tags:         sl.Tags,
+		Target:       sl.Target,
+		TargetPolicy: sl.TargetPolicy,
+		Action:       action,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if newName != name {
+		version.OldName = name
+	}
+	if err := v.dber.snapshot(ctx, version, v.keep); err != nil {
+		return fmt.Errorf("recording version: %w", err)
+	}
+
+	return nil
+}
+
+// changeKey is the context key a group of changes is stored under.
+type changeKey struct{}
+
+// change groups the db calls making up a single change, e.g. replacing a setlist's songs
+// by clearing it and adding each song, so it's recorded as one version.
+type change struct {
+	// action labels the versions recorded, falling back to the db method making the change
+	// when empty.
+	action string
+	// untracked changes aren't recorded at all.
+	untracked bool
+
+	mu       sync.Mutex
+	setlists map[string]bool
+}
+
+// first reports whether this is the first time the setlist is changed in the group.
+func (c *change) first(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.untracked || c.setlists[name] {
+		return false
+	}
+	c.setlists[name] = true
+
+	return true
+}
+
+// withChange returns a copy of ctx that groups the changes made with it, labelling them
+// with the provided action.
+func withChange(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, changeKey{}, &change{action: action, setlists: map[string]bool{}})
+}
+
+// untracked returns a copy of ctx whose changes aren't recorded as versions.
+func untracked(ctx context.Context) context.Context {
+	return context.WithValue(ctx, changeKey{}, &change{untracked: true})
+}
+
+// groupChanges wraps a handler, grouping the changes made while handling each request so
+// one request makes at most one version of each setlist.
+func groupChanges(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(rctx *fasthttp.RequestCtx) {
+		rctx.SetUserValue(changeKey{}, &change{setlists: map[string]bool{}})
+		h(rctx)
+	}
+}
+
+// findVersion returns the setlist's version with the provided ID, or errVersionNotFound.
+func findVersion(ctx context.Context, db versioner, name, id string) (*Version, error) {
+	vs, err := db.versions(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("looking up versions: %w", err)
+	}
+	for _, v := range vs {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+
+	return nil, errVersionNotFound
+}
+
+// restore sets a setlist's songs back to those of the version. The songs it had before
+// are recorded as a version of their own, so restoring can be undone too.
+func restore(ctx context.Context, db replacer, v *Version) error {
+	return replace(withChange(ctx, "restore"), db, v.Setlist, v.Songs)
+}
+
+// undo reverts the last change made to a setlist by restoring its most recent version,
+// which is then forgotten so undoing again steps further back. A deleted setlist is
+// restored from the trash so it isn't left in the trash too, failing with errSetlistExists
+// if its name has been taken since, and a renamed setlist is renamed back, failing with
+// errRenamedOver if its old name has been taken since. Otherwise its songs, tags and
+// target are set back to the version's. The version restored is returned, or nil if
+// there's nothing to undo.
+func undo(ctx context.Context, db undoer, name string) (*Version, error) {
+	vs, err := db.versions(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("looking up versions: %w", err)
+	}
+	if len(vs) == 0 {
+		return nil, nil
+	}
+
+	last := vs[0]
+	ctx = untracked(ctx)
+	trashed, err := deletedSetlist(ctx, db, last)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case trashed != nil:
+		if _, err := restoreTrashed(ctx, db, trashed.ID, ""); err != nil {
+			return nil, err
+		}
+	case last.OldName != "" && last.OldName != name:
+		if err := renameBack(ctx, db, name, last.OldName); err != nil {
+			return nil, err
+		}
+	default:
+		if err := revert(ctx, db, name, last); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.forget(ctx, name, last.ID); err != nil {
+		return nil, fmt.Errorf("forgetting version: %w", err)
+	}
+
+	return last, nil
+}
+
+// renameBack gives a renamed setlist its old name back, failing with errRenamedOver if a
+// setlist has been created under the old name since.
+func renameBack(ctx context.Context, db undoer, name, oldName string) error {
+	taken, err := db.find(ctx, oldName)
+	if err != nil {
+		return fmt.Errorf("looking up setlist: %w", err)
+	}
+	if taken != nil {
+		return errRenamedOver
+	}
+	if _, err := db.update(ctx, name, oldName); err != nil {
+		return fmt.Errorf("renaming setlist: %w", err)
+	}
+
+	return nil
+}
+
+// revert sets the setlist's songs back to the version's, along with its tags and target
+// if they've changed since.
+func revert(ctx context.Context, db undoer, name string, v *Version) error {
+	sl, err := db.find(ctx, name)
+	if err != nil {
+		return fmt.Errorf("looking up setlist: %w", err)
+	}
+	if err := replace(ctx, db, name, v.Songs); err != nil {
+		return err
+	}
+
+	var current Setlist
+	if sl != nil {
+		current = *sl
+	}
+	if !slices.Equal(current.Tags, v.Tags) {
+		if err := db.tag(ctx, name, v.Tags); err != nil {
+			return fmt.Errorf("tagging setlist: %w", err)
+		}
+	}
+	if current.Target != v.Target || current.TargetPolicy != v.TargetPolicy {
+		if err := db.target(ctx, name, v.Target, v.TargetPolicy); err != nil {
+			return fmt.Errorf("setting setlist's target: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deletedSetlist returns the setlist in the trash the version was recorded for deleting,
+// or nil if the version wasn't recorded for a delete or the setlist has since expired.
+func deletedSetlist(ctx context.Context, db trasher, v *Version) (*TrashedSetlist, error) {
+	if v.Action != "delete" {
+		return nil, nil
+	}
+
+	trashed, err := db.trashed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("looking up trash: %w", err)
+	}
+	// the trash is ordered most recently deleted first
+	for _, t := range trashed {
+		if t.Setlist.Name == v.Setlist {
+			return t, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersioned(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	downFromTheSky := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+	current := func() *Setlist { return &Setlist{Name: "chill", Songs: []*Song{fatLip}} }
+	snapshot := func(action string) any {
+		return mock.MatchedBy(func(v *Version) bool {
+			return v.ID != "" && v.Setlist == "chill" && v.Action == action &&
+				assert.ObjectsAreEqual([]*Song{fatLip}, v.Songs)
+		})
+	}
+
+	testCases := []struct {
+		name        string
+		keep        int
+		ctx         func() context.Context
+		db          func(t *testing.T) *Mockdber
+		change      func(ctx context.Context, db dber) error
+		expectedErr error
+	}{
+		{
+			name: "records version before each change",
+			keep: 5,
+			ctx:  context.Background,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(current(), nil)
+				db.On("snapshot", mock.Anything, snapshot("add"), 5).Return(nil).Once()
+				db.On("add", mock.Anything, "chill", downFromTheSky).Return(nil)
+				db.On("snapshot", mock.Anything, snapshot("move"), 5).Return(nil).Once()
+				db.On("move", mock.Anything, "chill", 2, 1).Return(nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error {
+				if err := db.add(ctx, "chill", downFromTheSky); err != nil {
+					return err
+				}
+				return db.move(ctx, "chill", 2, 1)
+			},
+		},
+		{
+			name: "records grouped changes once",
+			keep: 5,
+			ctx:  func() context.Context { return withChange(context.Background(), "") },
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(current(), nil)
+				db.On("snapshot", mock.Anything, snapshot("clear"), 5).Return(nil).Once()
+				db.On("clear", mock.Anything, "chill").Return(nil)
+				db.On("add", mock.Anything, "chill", downFromTheSky).Return(nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error {
+				return replace(ctx, db, "chill", []*Song{downFromTheSky})
+			},
+		},
+		{
+			name: "labels versions with group's action",
+			keep: 5,
+			ctx:  func() context.Context { return withChange(context.Background(), "restore") },
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(current(), nil)
+				db.On("snapshot", mock.Anything, snapshot("restore"), 5).Return(nil)
+				db.On("delete", mock.Anything, "chill", time.Time{}).Return(nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error { return db.delete(ctx, "chill", time.Time{}) },
+		},
+		{
+			name: "records renames under the new name",
+			keep: 5,
+			ctx:  context.Background,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").
+					Return(&Setlist{Name: "chill", Songs: []*Song{fatLip}, Tags: []string{"metal"}, Target: 3600}, nil)
+				db.On("snapshot", mock.Anything, mock.MatchedBy(func(v *Version) bool {
+					return v.Setlist == "mellow" && v.OldName == "chill" && v.Action == "update" &&
+						assert.ObjectsAreEqual([]string{"metal"}, v.Tags) && v.Target == 3600
+				}), 5).Return(nil)
+				db.On("update", mock.Anything, "chill", "mellow").Return(&Setlist{Name: "mellow"}, nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error {
+				_, err := db.update(ctx, "chill", "mellow")
+				return err
+			},
+		},
+		{
+			name: "records tags and target changes",
+			keep: 5,
+			ctx:  context.Background,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(current(), nil)
+				db.On("snapshot", mock.Anything, snapshot("tag"), 5).Return(nil).Once()
+				db.On("tag", mock.Anything, "chill", []string{"metal"}).Return(nil)
+				db.On("snapshot", mock.Anything, snapshot("target"), 5).Return(nil).Once()
+				db.On("target", mock.Anything, "chill", 3600, targetPolicyWarn).Return(nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error {
+				if err := db.tag(ctx, "chill", []string{"metal"}); err != nil {
+					return err
+				}
+				return db.target(ctx, "chill", 3600, targetPolicyWarn)
+			},
+		},
+		{
+			name: "doesn't record untracked changes",
+			keep: 5,
+			ctx:  func() context.Context { return untracked(context.Background()) },
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("clear", mock.Anything, "chill").Return(nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error { return db.clear(ctx, "chill") },
+		},
+		{
+			name: "doesn't record when no versions are kept",
+			ctx:  context.Background,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("remove", mock.Anything, "chill", "Fat Lip", 0).Return(nil)
+
+				return db
+			},
+			change: func(ctx context.Context, db dber) error { return db.remove(ctx, "chill", "Fat Lip", 0) },
+		},
+		{
+			name: "doesn't change setlist when recording fails",
+			keep: 5,
+			ctx:  context.Background,
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("find", mock.Anything, "chill").Return(current(), nil)
+				db.On("snapshot", mock.Anything, mock.Anything, 5).Return(fmt.Errorf("it broke"))
+
+				return db
+			},
+			change:      func(ctx context.Context, db dber) error { return db.clear(ctx, "chill") },
+			expectedErr: fmt.Errorf("recording version: it broke"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &versioned{dber: tc.db(t), keep: tc.keep}
+
+			err := tc.change(tc.ctx(), v)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDiffSongs(t *testing.T) {
+	a := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	b := &Song{Artist: "Sum 41", Name: "In Too Deep"}
+	c := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+	d := &Song{Artist: "Dragonforce", Name: "Through the Fire and Flames"}
+
+	testCases := []struct {
+		name     string
+		from     []*Song
+		to       []*Song
+		expected *SetlistDiff
+	}{
+		{
+			name:     "same songs",
+			from:     []*Song{a, b},
+			to:       []*Song{{Artist: "sum 41", Name: "fat lip"}, b},
+			expected: &SetlistDiff{Added: []*DiffSong{}, Removed: []*DiffSong{}, Moved: []*DiffMove{}},
+		},
+		{
+			name: "added and removed songs",
+			from: []*Song{a, b, c},
+			to:   []*Song{a, c, d},
+			expected: &SetlistDiff{
+				Added:   []*DiffSong{{Song: d, Position: 3}},
+				Removed: []*DiffSong{{Song: b, Position: 2}},
+				Moved:   []*DiffMove{},
+			},
+		},
+		{
+			name: "only moved song is listed",
+			from: []*Song{a, b, c, d},
+			to:   []*Song{d, a, b, c},
+			expected: &SetlistDiff{
+				Added:   []*DiffSong{},
+				Removed: []*DiffSong{},
+				Moved:   []*DiffMove{{Song: d, From: 4, To: 1}},
+			},
+		},
+		{
+			name: "repeated songs",
+			from: []*Song{a, b, a},
+			to:   []*Song{a, b},
+			expected: &SetlistDiff{
+				Added:   []*DiffSong{},
+				Removed: []*DiffSong{{Song: a, Position: 3}},
+				Moved:   []*DiffMove{},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, diffSongs(tc.from, tc.to))
+		})
+	}
+}
+
+func TestUndoSetlist(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	versions := []*Version{
+		{ID: "2", Setlist: "chill", Songs: []*Song{fatLip}, Action: "clear"},
+		{ID: "1", Setlist: "chill", Action: "add"},
+	}
+
+	testCases := []struct {
+		name               string
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name: "restores most recent version",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return(versions, nil)
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill"}, nil).Once()
+				db.On("clear", mock.Anything, "chill").Return(nil)
+				db.On("add", mock.Anything, "chill", fatLip).Return(nil)
+				db.On("forget", mock.Anything, "chill", "2").Return(nil)
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{fatLip}}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"name":"chill","songs":[{"artist":"Sum 41","name":"Fat Lip"}],"unknown_durations":1}`,
+		},
+		{
+			name: "restores deleted setlist from trash",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				deleted := &Setlist{Name: "chill", Songs: []*Song{fatLip}, Tags: []string{"metal"}, Target: 3600}
+				db.On("versions", mock.Anything, "chill").Return([]*Version{
+					{ID: "3", Setlist: "chill", Songs: []*Song{fatLip}, Action: "delete"},
+				}, nil)
+				db.On("trashed", mock.Anything).Return([]*TrashedSetlist{
+					{ID: "65ecb0c0ffee", Setlist: deleted},
+					{ID: "65ecb0c0ffed", Setlist: &Setlist{Name: "chill"}},
+				}, nil)
+				db.On("find", mock.Anything, "chill").Return(nil, nil).Once()
+				db.On("untrash", mock.Anything, "65ecb0c0ffee", "chill").Return(deleted, nil)
+				db.On("forget", mock.Anything, "chill", "3").Return(nil)
+				db.On("find", mock.Anything, "chill").Return(deleted, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"name":"chill","songs":[{"artist":"Sum 41","name":"Fat Lip"}],"tags":["metal"],` +
+				`"target":3600,"remaining":3600,"unknown_durations":1}`,
+		},
+		{
+			name: "restores tags and target",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				tagged := &Setlist{Name: "chill", Songs: []*Song{fatLip}, Tags: []string{"chill"}, Target: 1800}
+				db.On("versions", mock.Anything, "chill").Return([]*Version{
+					{ID: "4", Setlist: "chill", Songs: []*Song{fatLip}, Tags: []string{"metal"}, Target: 3600, Action: "tag"},
+				}, nil)
+				db.On("find", mock.Anything, "chill").Return(tagged, nil).Twice()
+				db.On("clear", mock.Anything, "chill").Return(nil)
+				db.On("add", mock.Anything, "chill", fatLip).Return(nil)
+				db.On("tag", mock.Anything, "chill", []string{"metal"}).Return(nil)
+				db.On("target", mock.Anything, "chill", 3600, targetPolicy("")).Return(nil)
+				db.On("forget", mock.Anything, "chill", "4").Return(nil)
+				db.On("find", mock.Anything, "chill").
+					Return(&Setlist{Name: "chill", Songs: []*Song{fatLip}, Tags: []string{"metal"}, Target: 3600}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"name":"chill","songs":[{"artist":"Sum 41","name":"Fat Lip"}],"tags":["metal"],` +
+				`"target":3600,"remaining":3600,"unknown_durations":1}`,
+		},
+		{
+			name: "renames renamed setlist back",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return([]*Version{
+					{ID: "5", Setlist: "chill", OldName: "mellow", Songs: []*Song{fatLip}, Action: "update"},
+				}, nil)
+				db.On("find", mock.Anything, "mellow").Return(nil, nil).Once()
+				db.On("update", mock.Anything, "chill", "mellow").Return(&Setlist{Name: "mellow"}, nil)
+				db.On("forget", mock.Anything, "chill", "5").Return(nil)
+				db.On("find", mock.Anything, "mellow").Return(&Setlist{Name: "mellow", Songs: []*Song{fatLip}}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"name":"mellow","songs":[{"artist":"Sum 41","name":"Fat Lip"}],"unknown_durations":1}`,
+		},
+		{
+			name: "refuses renaming back over setlist with old name",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return([]*Version{
+					{ID: "5", Setlist: "chill", OldName: "mellow", Action: "update"},
+				}, nil)
+				db.On("find", mock.Anything, "mellow").Return(&Setlist{Name: "mellow"}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"error":"a setlist with the old name was created since it was renamed"}`,
+		},
+		{
+			name: "refuses restoring deleted setlist over one with its name",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return([]*Version{
+					{ID: "3", Setlist: "chill", Action: "delete"},
+				}, nil)
+				db.On("trashed", mock.Anything).Return([]*TrashedSetlist{{ID: "65ecb0c0ffee", Setlist: &Setlist{Name: "chill"}}}, nil)
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill"}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"error":"a setlist with that name was created since it was deleted, restore it from the trash under another name"}`,
+		},
+		{
+			name: "nothing to undo",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return(nil, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"error":"no changes to undo"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/setlist/undo?name=chill")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip"}
+	downFromTheSky := &Song{Artist: "Trivium", Name: "Down From the Sky"}
+	versions := []*Version{{ID: "1", Setlist: "chill", Songs: []*Song{fatLip}}}
+
+	testCases := []struct {
+		name               string
+		query              string
+		db                 func(t *testing.T) *Mockdber
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:  "diffs against current songs",
+			query: "?name=chill&from=1",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return(versions, nil)
+				db.On("find", mock.Anything, "chill").Return(&Setlist{Name: "chill", Songs: []*Song{downFromTheSky}}, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody: `{"added":[{"song":{"artist":"Trivium","name":"Down From the Sky"},"position":1}],` +
+				`"removed":[{"song":{"artist":"Sum 41","name":"Fat Lip"},"position":1}],"moved":[]}`,
+		},
+		{
+			name:  "unknown version",
+			query: "?name=chill&from=1&to=7",
+			db: func(t *testing.T) *Mockdber {
+				db := NewMockdber(t)
+
+				db.On("versions", mock.Anything, "chill").Return(versions, nil)
+
+				return db
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"error":"version not found"}`,
+		},
+		{
+			name:               "requires version to diff from",
+			query:              "?name=chill",
+			db:                 func(t *testing.T) *Mockdber { return NewMockdber(t) },
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"error":"a version to diff from is required"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer()
+			s.db = tc.db(t)
+			client := newTestServer(t, routes(s).Handler)
+
+			resp, err := client.Get(lh + "/v1/setlist/versions/diff" + tc.query)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
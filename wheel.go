@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math/rand/v2"
+)
+
+const (
+	// defaultWheelSegments is the number of songs shown on the wheel by default.
+	defaultWheelSegments = 12
+	// wheelHistoryStreams is the number of recent streams whose plays lower a song's
+	// chance of being picked.
+	wheelHistoryStreams = 10
+)
+
+// wheelRequest describes a spin of the song wheel.
+type wheelRequest struct {
+	// Filter limits the songs that can be picked, e.g. to cap their difficulty.
+	Filter *songFilter
+	// Exclude contains the keys of songs that can't be picked, as returned by songKey.
+	Exclude map[string]bool
+	// Segments is the number of songs shown on the wheel.
+	Segments int
+	Seed     uint64
+}
+
+// wheelEvent describes the outcome of a spin for an overlay to animate: the songs shown on
+// the wheel in order and the index of the one the wheel lands on.
+type wheelEvent struct {
+	Type     string  `json:"type"`
+	Segments []*Song `json:"segments"`
+	Pick     int     `json:"pick"`
+	Song     *Song   `json:"song"`
+	// Added reports whether the song was added to the temporary setlist.
+	Added bool `json:"added"`
+	// Refused is the reason the song couldn't be added to the temporary setlist, if any.
+	Refused string `json:"refused,omitempty"`
+}
+
+// spin picks a song from the pool, returning nil if no songs can be picked. Each song's
+// chance of being picked is weighted by how often it was played in the provided plays so
+// that songs played less often come up more.
+func spin(pool []*Song, plays []*Play, r *wheelRequest) *wheelEvent {
+	counts := map[string]int{}
+	for _, p := range plays {
+		counts[songKey(p.Song)]++
+	}
+
+	var (
+		candidates []*Song
+		weights    []float64
+		total      float64
+		seen       = map[string]bool{}
+	)
+	for _, s := range pool {
+		k := songKey(s)
+		if seen[k] || r.Exclude[k] || !r.Filter.match(s) {
+			continue
+		}
+		seen[k] = true
+
+		w := 1 / float64(1+counts[k])
+		candidates = append(candidates, s)
+		weights = append(weights, w)
+		total += w
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	rnd := rand.New(rand.NewPCG(r.Seed, r.Seed))
+
+	pick := len(candidates) - 1
+	for i, n := 0, rnd.Float64()*total; i < len(weights); i++ {
+		if n -= weights[i]; n < 0 {
+			pick = i
+			break
+		}
+	}
+
+	// fill the rest of the wheel with other candidates for show
+	segments := r.Segments
+	if segments <= 0 {
+		segments = defaultWheelSegments
+	}
+	others := make([]*Song, 0, len(candidates)-1)
+	others = append(others, candidates[:pick]...)
+	others = append(others, candidates[pick+1:]...)
+	rnd.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+	others = others[:min(len(others), segments-1)]
+
+	at := rnd.IntN(len(others) + 1)
+	e := &wheelEvent{
+		Type:     "wheel_spin",
+		Segments: make([]*Song, 0, len(others)+1),
+		Pick:     at,
+		Song:     candidates[pick],
+	}
+	e.Segments = append(e.Segments, others[:at]...)
+	e.Segments = append(e.Segments, candidates[pick])
+	e.Segments = append(e.Segments, others[at:]...)
+
+	return e
+}
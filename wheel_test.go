@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpin(t *testing.T) {
+	pool := []*Song{
+		{Artist: "DragonForce", Name: "Through the Fire and Flames", Difficulties: map[string]int{"guitar": 6}},
+		{Artist: "Trivium", Name: "Down From the Sky", Difficulties: map[string]int{"guitar": 5}},
+		{Artist: "Sum 41", Name: "Fat Lip", Difficulties: map[string]int{"guitar": 2}},
+		{Artist: "Avenged Sevenfold", Name: "Afterlife", Difficulties: map[string]int{"guitar": 5}},
+	}
+	maxDifficulty := 5
+
+	t.Run("picks only songs that aren't excluded or filtered out", func(t *testing.T) {
+		r := &wheelRequest{
+			Filter:  &songFilter{Instrument: "guitar", MaxDifficulty: &maxDifficulty},
+			Exclude: map[string]bool{songKey(pool[1]): true},
+		}
+
+		for seed := uint64(0); seed < 50; seed++ {
+			r.Seed = seed
+
+			e := spin(pool, nil, r)
+
+			require.NotNil(t, e)
+			assert.Contains(t, []string{"Fat Lip", "Afterlife"}, e.Song.Name)
+			assert.Len(t, e.Segments, 2)
+			assert.Equal(t, e.Song, e.Segments[e.Pick])
+			assert.Equal(t, "wheel_spin", e.Type)
+		}
+	})
+
+	t.Run("picks songs played less often more often", func(t *testing.T) {
+		var plays []*Play
+		for i := 0; i < 9; i++ {
+			plays = append(plays, &Play{Song: pool[2]})
+		}
+		r := &wheelRequest{Filter: &songFilter{}, Segments: 3}
+
+		picks := map[string]int{}
+		for seed := uint64(0); seed < 1000; seed++ {
+			r.Seed = seed
+
+			e := spin(pool, plays, r)
+
+			require.NotNil(t, e)
+			assert.Len(t, e.Segments, 3)
+			picks[e.Song.Name]++
+		}
+
+		assert.Less(t, picks["Fat Lip"], picks["Afterlife"]/3)
+	})
+
+	t.Run("returns nil when nothing can be picked", func(t *testing.T) {
+		r := &wheelRequest{Filter: &songFilter{Artist: "Slayer"}}
+
+		assert.Nil(t, spin(pool, nil, r))
+	})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"cmp"
+	"html/template"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// Wrapped is a recap of a streamer's played history over a year or another range of time.
+type Wrapped struct {
+	Streamer      string       `json:"streamer"`
+	Since         time.Time    `json:"since"`
+	Until         time.Time    `json:"until"`
+	SongsPlayed   int          `json:"songs_played"`
+	Streams       int          `json:"streams"`
+	TopSongs      []*SongCount `json:"top_songs"`
+	TopArtists    []*NameCount `json:"top_artists"`
+	TopRequesters []*NameCount `json:"top_requesters"`
+	// Hours is the length of the songs played in hours, leaving out songs whose durations
+	// aren't known.
+	Hours float64 `json:"hours"`
+	// LongestStreaks are the songs played on the most streams in a row.
+	LongestStreaks []*Streak `json:"longest_streaks"`
+	// FirstTimeSongs are the songs played for the first time, in the order they were.
+	FirstTimeSongs []*Song `json:"first_time_songs"`
+}
+
+// Streak is a song played on a number of streams in a row.
+type Streak struct {
+	Song    *Song `json:"song"`
+	Streams int   `json:"streams"`
+}
+
+// wrap recaps the plays from since up to until, listing up to limit entries in each top
+// list. history must include every play before until so songs played for the first time
+// can be told apart from those played before.
+func wrap(streamer string, history []*Play, since, until time.Time, limit int) *Wrapped {
+	history = slices.Clone(history)
+	slices.SortStableFunc(history, func(a, b *Play) int { return a.PlayedAt.Compare(b.PlayedAt) })
+
+	before := map[string]bool{}
+	var plays []*Play
+	for _, p := range history {
+		switch {
+		case p.PlayedAt.Before(since):
+			before[songKey(p.Song)] = true
+		case p.PlayedAt.Before(until):
+			plays = append(plays, p)
+		}
+	}
+
+	st := stats(plays, nil, limit)
+	w := &Wrapped{
+		Streamer:       streamer,
+		Since:          since,
+		Until:          until,
+		SongsPlayed:    st.Plays,
+		Streams:        st.Streams,
+		TopSongs:       st.TopSongs,
+		TopArtists:     st.TopArtists,
+		TopRequesters:  st.TopRequesters,
+		LongestStreaks: longestStreaks(plays, limit),
+		FirstTimeSongs: []*Song{},
+	}
+
+	var seconds int
+	for _, p := range plays {
+		seconds += p.Song.Duration
+		if k := songKey(p.Song); !before[k] {
+			before[k] = true
+			w.FirstTimeSongs = append(w.FirstTimeSongs, p.Song)
+		}
+	}
+	w.Hours = math.Round(float64(seconds)/360) / 10
+
+	return w
+}
+
+// longestStreaks returns up to limit of the songs played on the most streams in a row,
+// leaving out songs that were never played on consecutive streams. plays must be sorted
+// by when they were played.
+func longestStreaks(plays []*Play, limit int) []*Streak {
+	var streams []string
+	index := map[string]int{}
+	for _, p := range plays {
+		if _, ok := index[p.Stream]; !ok {
+			index[p.Stream] = len(streams)
+			streams = append(streams, p.Stream)
+		}
+	}
+
+	type run struct {
+		streak *Streak
+		// last is the index of the last stream in the song's current run and current is the
+		// number of streams in it.
+		last    int
+		current int
+	}
+	var runs []*run
+	bySong := map[string]*run{}
+	for _, p := range plays {
+		i := index[p.Stream]
+		r, ok := bySong[songKey(p.Song)]
+		switch {
+		case !ok:
+			r = &run{streak: &Streak{Song: p.Song, Streams: 1}, last: i, current: 1}
+			bySong[songKey(p.Song)] = r
+			runs = append(runs, r)
+		case i == r.last:
+			continue
+		case i == r.last+1:
+			r.current++
+		default:
+			r.current = 1
+		}
+		r.last = i
+		r.streak.Streams = max(r.streak.Streams, r.current)
+	}
+
+	streaks := []*Streak{}
+	for _, r := range runs {
+		if r.streak.Streams > 1 {
+			streaks = append(streaks, r.streak)
+		}
+	}
+	slices.SortStableFunc(streaks, func(a, b *Streak) int { return cmp.Compare(b.Streams, a.Streams) })
+
+	return streaks[:min(len(streaks), limit)]
+}
+
+// writeHTML renders the recap as a standalone HTML page that can be shared as is.
+func (w *Wrapped) writeHTML(out io.Writer) error {
+	return wrappedTemplate.Execute(out, w)
+}
+
+var wrappedTemplate = template.Must(template.New("wrapped").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Streamer}} Wrapped</title>
+<style>
+body { margin: 0; padding: 2rem; background: #18181b; color: #efeff1; font-family: system-ui, sans-serif; }
+main { max-width: 40rem; margin: 0 auto; }
+h1 { color: #9146ff; margin-bottom: 0; }
+.range { color: #adadb8; margin-top: 0.25rem; }
+.totals { display: flex; gap: 1rem; flex-wrap: wrap; }
+.total { flex: 1; padding: 1rem; border-radius: 0.5rem; background: #26262c; text-align: center; }
+.total strong { display: block; font-size: 2rem; color: #9146ff; }
+section { margin-top: 2rem; }
+ol { padding-left: 1.5rem; }
+li { margin: 0.25rem 0; }
+.count { color: #adadb8; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Streamer}} Wrapped</h1>
+<p class="range">{{.Since.Format "Jan 2, 2006"}} to {{(.Until.Add -1).Format "Jan 2, 2006"}}</p>
+<div class="totals">
+<div class="total"><strong>{{.SongsPlayed}}</strong>songs played</div>
+<div class="total"><strong>{{.Streams}}</strong>streams</div>
+<div class="total"><strong>{{printf "%.1f" .Hours}}</strong>hours of music</div>
+<div class="total"><strong>{{len .FirstTimeSongs}}</strong>first time songs</div>
+</div>
+{{- with .TopSongs}}
+<section>
+<h2>Top Songs</h2>
+<ol>
+{{- range .}}
+<li>{{.Song.Name}} by {{.Song.Artist}} <span class="count">{{.Count}} play{{if ne .Count 1}}s{{end}}</span></li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+{{- with .TopArtists}}
+<section>
+<h2>Top Artists</h2>
+<ol>
+{{- range .}}
+<li>{{.Name}} <span class="count">{{.Count}} play{{if ne .Count 1}}s{{end}}</span></li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+{{- with .TopRequesters}}
+<section>
+<h2>Top Requesters</h2>
+<ol>
+{{- range .}}
+<li>{{.Name}} <span class="count">{{.Count}} request{{if ne .Count 1}}s{{end}}</span></li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+{{- with .LongestStreaks}}
+<section>
+<h2>Longest Streaks</h2>
+<ol>
+{{- range .}}
+<li>{{.Song.Name}} by {{.Song.Artist}} <span class="count">{{.Streams}} streams in a row</span></li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+{{- with .FirstTimeSongs}}
+<section>
+<h2>First Time Songs</h2>
+<ol>
+{{- range .}}
+<li>{{.Name}} by {{.Artist}}</li>
+{{- end}}
+</ol>
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap(t *testing.T) {
+	since := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(1, 0, 0)
+	fatLip := &Song{Artist: "Sum 41", Name: "Fat Lip", Duration: 180}
+	downFromTheSky := &Song{Artist: "Trivium", Name: "Down From the Sky", Duration: 334, RequestedBy: "cooler_user"}
+	ttfaf := &Song{Artist: "DragonForce", Name: "Through the Fire & Flames", Duration: 441}
+	play := func(s *Song, stream string, day int) *Play {
+		return &Play{Song: s, Stream: stream, PlayedAt: since.AddDate(0, 0, day)}
+	}
+
+	history := []*Play{
+		play(fatLip, "a", 3),
+		play(downFromTheSky, "a", 3),
+		play(fatLip, "b", 10),
+		play(fatLip, "b", 10),
+		play(ttfaf, "b", 10),
+		play(fatLip, "c", 17),
+		play(downFromTheSky, "c", 17),
+		play(downFromTheSky, "d", 24),
+		// played before the year, so not a first time song
+		play(ttfaf, "z", -30),
+		// played after the year
+		play(fatLip, "e", 400),
+	}
+
+	w := wrap("doomedfingers", history, since, until, 10)
+	assert.Equal(t, &Wrapped{
+		Streamer:       "doomedfingers",
+		Since:          since,
+		Until:          until,
+		SongsPlayed:    8,
+		Streams:        4,
+		TopSongs:       []*SongCount{{Song: fatLip, Count: 4}, {Song: downFromTheSky, Count: 3}, {Song: ttfaf, Count: 1}},
+		TopArtists:     []*NameCount{{Name: "Sum 41", Count: 4}, {Name: "Trivium", Count: 3}, {Name: "DragonForce", Count: 1}},
+		TopRequesters:  []*NameCount{{Name: "cooler_user", Count: 3}},
+		Hours:          0.6,
+		LongestStreaks: []*Streak{{Song: fatLip, Streams: 3}, {Song: downFromTheSky, Streams: 2}},
+		FirstTimeSongs: []*Song{fatLip, downFromTheSky},
+	}, w)
+
+	t.Run("html", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, w.writeHTML(&buf))
+
+		golden := filepath.Join("testdata", "wrapped", "wrapped.html")
+		if *updateGolden {
+			require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+		}
+		expected, err := os.ReadFile(golden)
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), buf.String())
+	})
+}